@@ -0,0 +1,79 @@
+package mongodb
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff describes an exponential backoff with jitter. The zero value is replaced by
+// DefaultBackoff wherever a Backoff is accepted.
+type Backoff struct {
+	// Initial is the delay before the second attempt.
+	Initial time.Duration
+	// Max caps the delay between two attempts.
+	Max time.Duration
+	// Multiplier is applied to the delay after every attempt.
+	Multiplier float64
+	// Jitter is the fraction of the delay, between 0 and 1, which is randomized.
+	Jitter float64
+	// MaxAttempts is the maximum number of attempts, 0 means no limit.
+	MaxAttempts int
+}
+
+// DefaultBackoff returns the backoff used when none is configured.
+func DefaultBackoff() Backoff {
+	return Backoff{
+		Initial:     100 * time.Millisecond,
+		Max:         5 * time.Second,
+		Multiplier:  2,
+		Jitter:      0.2,
+		MaxAttempts: 5,
+	}
+}
+
+func (b Backoff) orDefault() Backoff {
+	if b == (Backoff{}) {
+		return DefaultBackoff()
+	}
+	return b
+}
+
+// Delay returns how long to wait after the attempt number passed, starting at 0.
+func (b Backoff) Delay(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(attempt))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+
+	if b.Jitter > 0 {
+		jitter := math.Min(b.Jitter, 1)
+		delay = delay * (1 - jitter + 2*jitter*rand.Float64())
+	}
+
+	return time.Duration(delay)
+}
+
+// Retry reports whether another attempt is allowed after the attempt number passed.
+func (b Backoff) Retry(attempt int) bool {
+	return b.MaxAttempts <= 0 || attempt+1 < b.MaxAttempts
+}
+
+// Wait sleeps the delay of the attempt passed, returning earlier if ctx is done.
+func (b Backoff) Wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(b.Delay(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
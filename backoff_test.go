@@ -0,0 +1,42 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+
+	assert.Equal(t, 100*time.Millisecond, b.Delay(0))
+	assert.Equal(t, 200*time.Millisecond, b.Delay(1))
+	assert.Equal(t, 800*time.Millisecond, b.Delay(3))
+	assert.Equal(t, time.Second, b.Delay(4), "delay is capped by max")
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := b.Delay(1)
+		assert.GreaterOrEqual(t, d, 100*time.Millisecond)
+		assert.LessOrEqual(t, d, 300*time.Millisecond)
+	}
+}
+
+func TestBackoffRetry(t *testing.T) {
+	b := Backoff{MaxAttempts: 3}
+
+	assert.True(t, b.Retry(0))
+	assert.True(t, b.Retry(1))
+	assert.False(t, b.Retry(2))
+	assert.True(t, Backoff{}.Retry(1000), "no max attempts means retrying forever")
+}
+
+func TestBackoffWaitHonoursContext(t *testing.T) {
+	ctx, cl := context.WithCancel(context.TODO())
+	cl()
+
+	err := Backoff{Initial: time.Hour}.Wait(ctx, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+}
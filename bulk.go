@@ -0,0 +1,463 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// Limits of a single write command enforced by the server.
+const (
+	DefaultMaxBatchSize  = 100000
+	DefaultMaxBatchBytes = 48000000
+)
+
+// ErrBulkWrite is matched by the error returned by DoBulkWrite when some operations failed.
+var ErrBulkWrite = errors.New("bulk write failed")
+
+// WriteModel is an operation of a bulk write: InsertModel, UpdateModel, UpdateModelOf,
+// ReplaceModel or DeleteModel.
+type WriteModel interface {
+	writeModel(ctx context.Context, db, col string) (mongo.WriteModel, int, error)
+}
+
+// InsertModel inserts Document. A new ObjectID is set as _id when Document has none, so it
+// is known before sending, see BulkResult.InsertedIDs.
+type InsertModel[T any] struct {
+	Document T
+}
+
+func (m InsertModel[T]) writeModel(ctx context.Context, db, col string) (mongo.WriteModel, int, error) {
+	doc := stampInsert(m.Document)
+	if err := beforeInsert(ctx, &doc); err != nil {
+		return nil, 0, err
+	}
+
+	raw, err := withID(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return mongo.NewInsertOneModel().SetDocument(raw), len(raw), nil
+}
+
+// withID encodes doc, adding a new ObjectID as _id first when it has none.
+func withID(doc any) (bson.Raw, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := bson.Raw(raw).LookupErr("_id"); err == nil {
+		return raw, nil
+	}
+
+	index, encoded := bsoncore.AppendDocumentStart(nil)
+	encoded = bsoncore.AppendObjectIDElement(encoded, "_id", primitive.NewObjectID())
+	encoded = append(encoded, raw[4:len(raw)-1]...)
+	if encoded, err = bsoncore.AppendDocumentEnd(encoded, index); err != nil {
+		return nil, err
+	}
+
+	return encoded, nil
+}
+
+// UpdateModel applies Update to the first document matching Filter, or to all of them when
+// Many is true. Soft deleted documents are skipped, like in DoUpdateOne.
+type UpdateModel struct {
+	Filter       any
+	Update       any
+	Many         bool
+	Upsert       bool
+	ArrayFilters []any
+}
+
+func (m UpdateModel) writeModel(ctx context.Context, db, col string) (mongo.WriteModel, int, error) {
+	if err := validateUpdate(m.Update); err != nil {
+		return nil, 0, err
+	}
+
+	filter := visibleFilter(ctx, db, col, orEmptyFilter(m.Filter))
+
+	size, err := bsonSize(filter, m.Update)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if m.Many {
+		um := mongo.NewUpdateManyModel().SetFilter(filter).SetUpdate(m.Update).SetUpsert(m.Upsert)
+		if m.ArrayFilters != nil {
+			um.SetArrayFilters(options.ArrayFilters{Filters: m.ArrayFilters})
+		}
+		return um, size, nil
+	}
+
+	um := mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(m.Update).SetUpsert(m.Upsert)
+	if m.ArrayFilters != nil {
+		um.SetArrayFilters(options.ArrayFilters{Filters: m.ArrayFilters})
+	}
+	return um, size, nil
+}
+
+// UpdateModelOf is UpdateModel for documents T: the version and updated timestamp of T, if
+// any, are updated too, like in DoUpdateOneOf.
+type UpdateModelOf[T any] UpdateModel
+
+func (m UpdateModelOf[T]) writeModel(ctx context.Context, db, col string) (mongo.WriteModel, int, error) {
+	if err := validateUpdate(m.Update); err != nil {
+		return nil, 0, err
+	}
+	if err := beforeUpdate(ctx, &m.Update); err != nil {
+		return nil, 0, err
+	}
+
+	update, err := prepareUpdate[T](m.Update)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	m.Update = update
+	return UpdateModel(m).writeModel(ctx, db, col)
+}
+
+// ReplaceModel replaces the first document matching Filter with Replacement. Soft deleted
+// documents are skipped.
+type ReplaceModel[T any] struct {
+	Filter      any
+	Replacement T
+	Upsert      bool
+}
+
+func (m ReplaceModel[T]) writeModel(ctx context.Context, db, col string) (mongo.WriteModel, int, error) {
+	if err := validateReplacement(m.Replacement); err != nil {
+		return nil, 0, err
+	}
+
+	doc := stampReplace(m.Replacement)
+	if err := beforeUpdate(ctx, &doc); err != nil {
+		return nil, 0, err
+	}
+
+	filter := visibleFilter(ctx, db, col, orEmptyFilter(m.Filter))
+
+	size, err := bsonSize(filter, doc)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(doc).SetUpsert(m.Upsert), size, nil
+}
+
+// DeleteModel deletes the first document matching Filter, or all of them when Many is true.
+// In collections with soft delete enabled the documents are marked as deleted instead, and
+// counted in BulkResult.ModifiedCount.
+type DeleteModel struct {
+	Filter any
+	Many   bool
+	// AllowFullCollection accepts a nil or empty Filter, see WithAllowFullCollection. Without
+	// it such a model fails with ErrEmptyFilter.
+	AllowFullCollection bool
+}
+
+func (m DeleteModel) writeModel(ctx context.Context, db, col string) (mongo.WriteModel, int, error) {
+	filter, err := guardEmptyFilter(m.Filter, deleteSettings{allowFullCollection: m.AllowFullCollection})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if softDeleteEnabled(db, col) {
+		filter, update := notDeleted(filter), softDeleteUpdate(ctx)
+
+		size, err := bsonSize(filter, update)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if m.Many {
+			return mongo.NewUpdateManyModel().SetFilter(filter).SetUpdate(update), size, nil
+		}
+		return mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update), size, nil
+	}
+
+	size, err := bsonSize(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if m.Many {
+		return mongo.NewDeleteManyModel().SetFilter(filter), size, nil
+	}
+
+	return mongo.NewDeleteOneModel().SetFilter(filter), size, nil
+}
+
+// BulkOptions configures DoBulkWrite.
+type BulkOptions struct {
+	// Unordered keeps going after a failed operation and lets the server apply them in any
+	// order. By default the bulk write stops on the first failure.
+	Unordered bool
+	// MaxBatchSize is the maximum number of operations sent in one command, DefaultMaxBatchSize
+	// if not set.
+	MaxBatchSize int
+	// MaxBatchBytes is the maximum size of the documents sent in one command,
+	// DefaultMaxBatchBytes if not set. An operation bigger than that is sent alone.
+	MaxBatchBytes int
+}
+
+func (o *BulkOptions) ordered() bool {
+	return o == nil || !o.Unordered
+}
+
+func (o *BulkOptions) limits() (count, bytes int) {
+	count, bytes = DefaultMaxBatchSize, DefaultMaxBatchBytes
+	if o != nil && o.MaxBatchSize > 0 {
+		count = o.MaxBatchSize
+	}
+	if o != nil && o.MaxBatchBytes > 0 {
+		bytes = o.MaxBatchBytes
+	}
+	return count, bytes
+}
+
+// BulkResult sums the results of every batch of a bulk write.
+type BulkResult struct {
+	InsertedCount int64
+	MatchedCount  int64
+	ModifiedCount int64
+	DeletedCount  int64
+	UpsertedCount int64
+	// InsertedIDs maps the index of the input InsertModels which inserted a document to its _id.
+	InsertedIDs map[int]any
+	// UpsertedIDs maps the index of the input models which upserted a document to its _id.
+	UpsertedIDs map[int]any
+	// Errors are the failed operations, also returned as a *BulkWriteError.
+	Errors []*BulkOperationError
+	// StoppedAt is the index of the operation the bulk write stopped at, -1 when it did not
+	// stop: the failed operation of an ordered run, or the first operation of a batch which
+	// failed as a whole, like on a network error. The operations after it were never executed.
+	StoppedAt int
+}
+
+func newBulkResult(writes []mongo.WriteModel) *BulkResult {
+	result := &BulkResult{InsertedIDs: make(map[int]any), UpsertedIDs: make(map[int]any), StoppedAt: -1}
+
+	for i, write := range writes {
+		insert, ok := write.(*mongo.InsertOneModel)
+		if !ok {
+			continue
+		}
+
+		var id any
+		if raw, ok := insert.Document.(bson.Raw); ok && raw.Lookup("_id").Unmarshal(&id) == nil {
+			result.InsertedIDs[i] = id
+		}
+	}
+
+	return result
+}
+
+func (r *BulkResult) add(res *mongo.BulkWriteResult, offset int) {
+	if res == nil {
+		return
+	}
+
+	r.InsertedCount += res.InsertedCount
+	r.MatchedCount += res.MatchedCount
+	r.ModifiedCount += res.ModifiedCount
+	r.DeletedCount += res.DeletedCount
+	r.UpsertedCount += res.UpsertedCount
+
+	for index, id := range res.UpsertedIDs {
+		r.UpsertedIDs[offset+int(index)] = id
+	}
+}
+
+// fail records the failure of the operation at index.
+func (r *BulkResult) fail(index int, err error) {
+	r.Errors = append(r.Errors, &BulkOperationError{Index: index, Err: err})
+	delete(r.InsertedIDs, index)
+}
+
+// stop records that the operations from index on were not executed, or failed.
+func (r *BulkResult) stop(index int) {
+	r.StoppedAt = index
+	for i := range r.InsertedIDs {
+		if i >= index {
+			delete(r.InsertedIDs, i)
+		}
+	}
+}
+
+// BulkOperationError is the failure of one operation of a bulk write.
+type BulkOperationError struct {
+	// Index is the position of the model in the input.
+	Index int
+	// Err is one of the typed errors of the package, like *DuplicateKeyError, or the
+	// mongo.WriteError sent by the server.
+	Err error
+}
+
+func (e *BulkOperationError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BulkOperationError) Unwrap() error { return e.Err }
+
+// BulkWriteError is returned by DoBulkWrite when some operations failed. It matches
+// ErrBulkWrite and any error matched by one of the failed operations, so
+// errors.Is(err, ErrDuplicateKey) reports whether any of them hit a unique index.
+type BulkWriteError struct {
+	Errors []*BulkOperationError
+}
+
+func (e *BulkWriteError) Error() string {
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("%v: %v", ErrBulkWrite, e.Errors[0])
+	}
+	return fmt.Sprintf("%v: %d operations failed, first %v", ErrBulkWrite, len(e.Errors), e.Errors[0])
+}
+
+func (e *BulkWriteError) Is(target error) bool {
+	if target == ErrBulkWrite {
+		return true
+	}
+
+	for _, opErr := range e.Errors {
+		if errors.Is(opErr, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BulkResultFunc returns the result of a bulk write.
+type BulkResultFunc func(context.Context, *mongo.Client) (*BulkResult, error)
+
+// DoBulkWrite runs models against col, splitting them in batches the server accepts. The
+// result is returned even on failure, with the operations which made it and where it
+// stopped. Failed operations are reported as a *BulkWriteError; any other error aborts the
+// remaining batches.
+func DoBulkWrite(db, col string, models []WriteModel, opts *BulkOptions) BulkResultFunc {
+	return Instrument(Operation{Name: "bulkWrite", Database: db, Collection: col}, func(ctx context.Context, c *mongo.Client) (*BulkResult, error) {
+		writes, sizes, err := toWriteModels(ctx, db, col, models)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			result             = newBulkResult(writes)
+			ordered            = opts.ordered()
+			bo                 = options.BulkWrite().SetOrdered(ordered)
+			coll               = c.Database(db).Collection(col)
+			maxCount, maxBytes = opts.limits()
+		)
+
+		for _, b := range splitBatches(sizes, maxCount, maxBytes) {
+			res, err := coll.BulkWrite(ctx, writes[b.start:b.end], bo)
+			result.add(res, b.start)
+			if err == nil {
+				continue
+			}
+
+			var bwe mongo.BulkWriteException
+			if !errors.As(err, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0 {
+				result.stop(b.start)
+				return result, translateError(err)
+			}
+
+			for _, we := range bwe.WriteErrors {
+				index := b.start + we.Index
+				opErr := typedWriteError(index, we.WriteError, err)
+				if opErr == nil {
+					opErr = we.WriteError
+				}
+				result.fail(index, opErr)
+			}
+
+			if ordered {
+				result.stop(b.start + bwe.WriteErrors[0].Index)
+				break
+			}
+		}
+
+		if len(result.Errors) > 0 {
+			return result, &BulkWriteError{Errors: result.Errors}
+		}
+
+		return result, nil
+	})
+}
+
+func toWriteModels(ctx context.Context, db, col string, models []WriteModel) ([]mongo.WriteModel, []int, error) {
+	var (
+		writes = make([]mongo.WriteModel, len(models))
+		sizes  = make([]int, len(models))
+	)
+
+	for i, model := range models {
+		if model == nil {
+			return nil, nil, fmt.Errorf("model %d: nil write model", i)
+		}
+
+		write, size, err := model.writeModel(ctx, db, col)
+		if err != nil {
+			return nil, nil, fmt.Errorf("model %d: %w", i, err)
+		}
+
+		writes[i], sizes[i] = write, size
+	}
+
+	return writes, sizes, nil
+}
+
+type batch struct {
+	start, end int
+}
+
+// splitBatches groups consecutive operations in batches of at most maxCount operations and
+// maxBytes bytes.
+func splitBatches(sizes []int, maxCount, maxBytes int) []batch {
+	var (
+		batches []batch
+		current batch
+		bytes   int
+	)
+
+	for i, size := range sizes {
+		full := current.end-current.start >= maxCount || bytes+size > maxBytes
+		if current.end > current.start && full {
+			batches = append(batches, current)
+			current, bytes = batch{start: i, end: i}, 0
+		}
+
+		current.end++
+		bytes += size
+	}
+
+	if current.end > current.start {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
+
+// bsonSize returns the encoded size of docs.
+func bsonSize(docs ...any) (int, error) {
+	size := 0
+	for _, doc := range docs {
+		_, data, err := bson.MarshalValue(doc)
+		if err != nil {
+			return 0, err
+		}
+		size += len(data)
+	}
+
+	return size, nil
+}
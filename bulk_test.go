@@ -0,0 +1,160 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestSplitBatches(t *testing.T) {
+	var testCases = []struct {
+		description string
+		sizes       []int
+		maxCount    int
+		maxBytes    int
+		want        []batch
+	}{
+		{
+			description: "nothing to split",
+			sizes:       nil,
+			maxCount:    10,
+			maxBytes:    100,
+		},
+		{
+			description: "split by count",
+			sizes:       []int{1, 1, 1, 1, 1},
+			maxCount:    2,
+			maxBytes:    100,
+			want:        []batch{{0, 2}, {2, 4}, {4, 5}},
+		},
+		{
+			description: "split by bytes",
+			sizes:       []int{40, 40, 40, 10},
+			maxCount:    10,
+			maxBytes:    100,
+			want:        []batch{{0, 2}, {2, 4}},
+		},
+		{
+			description: "operations bigger than the limit go alone",
+			sizes:       []int{10, 500, 10},
+			maxCount:    10,
+			maxBytes:    100,
+			want:        []batch{{0, 1}, {1, 2}, {2, 3}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.want, splitBatches(tc.sizes, tc.maxCount, tc.maxBytes))
+		})
+	}
+}
+
+func TestToWriteModels(t *testing.T) {
+	filter := bson.D{{Key: "name", Value: "x"}}
+
+	t.Run("every model is converted", func(t *testing.T) {
+		writes, sizes, err := toWriteModels(context.TODO(), "db", "col", []WriteModel{
+			InsertModel[bson.D]{Document: filter},
+			UpdateModel{Filter: filter, Update: bson.D{{Key: "$set", Value: filter}}, Many: true},
+			ReplaceModel[bson.D]{Filter: filter, Replacement: filter, Upsert: true},
+			DeleteModel{Filter: filter},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.IsType(t, &mongo.InsertOneModel{}, writes[0])
+		assert.IsType(t, &mongo.UpdateManyModel{}, writes[1])
+		assert.IsType(t, &mongo.ReplaceOneModel{}, writes[2])
+		assert.IsType(t, &mongo.DeleteOneModel{}, writes[3])
+		assert.Equal(t, len(writes[0].(*mongo.InsertOneModel).Document.(bson.Raw)), sizes[0])
+	})
+
+	t.Run("invalid models report their index", func(t *testing.T) {
+		_, _, err := toWriteModels(context.TODO(), "db", "col", []WriteModel{
+			DeleteModel{Filter: filter},
+			UpdateModel{Filter: filter, Update: filter},
+		})
+
+		assert.ErrorIs(t, err, ErrInvalidUpdate)
+		assert.Contains(t, err.Error(), "model 1")
+	})
+
+	t.Run("deletes of the whole collection must be allowed", func(t *testing.T) {
+		for _, empty := range []any{nil, bson.D{}, bson.M{}} {
+			_, _, err := toWriteModels(context.TODO(), "db", "col", []WriteModel{DeleteModel{Filter: empty, Many: true}})
+			assert.ErrorIs(t, err, ErrEmptyFilter)
+		}
+
+		writes, _, err := toWriteModels(context.TODO(), "db", "col", []WriteModel{DeleteModel{Many: true, AllowFullCollection: true}})
+		if assert.NoError(t, err) {
+			assert.Equal(t, bson.D{}, writes[0].(*mongo.DeleteManyModel).Filter)
+		}
+	})
+}
+
+func TestBulkResult(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	writes, _, err := toWriteModels(context.TODO(), "db", "col", []WriteModel{
+		InsertModel[bson.D]{Document: bson.D{{Key: "_id", Value: id}, {Key: "name", Value: "x"}}},
+		DeleteModel{Filter: bson.D{{Key: "name", Value: "x"}}},
+		InsertModel[Person]{Document: Person{Name: "y"}},
+		InsertModel[bson.M]{Document: bson.M{"_id": "z"}},
+		InsertModel[bson.M]{Document: bson.M{"name": "w"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := newBulkResult(writes)
+	assert.Equal(t, -1, result.StoppedAt)
+	assert.Equal(t, id, result.InsertedIDs[0], "the _id of the document is kept")
+	assert.Equal(t, "z", result.InsertedIDs[3])
+	assert.Len(t, result.InsertedIDs, 4)
+
+	generated, ok := result.InsertedIDs[2].(primitive.ObjectID)
+	if assert.True(t, ok, "an ObjectID is generated for documents without _id") {
+		raw := writes[2].(*mongo.InsertOneModel).Document.(bson.Raw)
+		assert.Equal(t, generated, raw.Lookup("_id").ObjectID(), "and sent")
+		assert.Equal(t, "y", raw.Lookup("name").StringValue())
+	}
+
+	result.fail(2, ErrDuplicateKey)
+	result.stop(2)
+	assert.Equal(t, 2, result.StoppedAt)
+	assert.Equal(t, map[int]any{0: id}, result.InsertedIDs, "neither the failed insert nor the ones after it inserted anything")
+	assert.Equal(t, []*BulkOperationError{{Index: 2, Err: ErrDuplicateKey}}, result.Errors)
+}
+
+func TestBulkWriteError(t *testing.T) {
+	err := error(&BulkWriteError{Errors: []*BulkOperationError{
+		{Index: 2, Err: mongo.WriteError{Code: 2}},
+		{Index: 5, Err: &DuplicateKeyError{Index: 5}},
+	}})
+
+	assert.ErrorIs(t, err, ErrBulkWrite)
+	assert.ErrorIs(t, err, ErrDuplicateKey)
+	assert.False(t, errors.Is(err, ErrValidation))
+}
+
+func TestUpdateModelOf(t *testing.T) {
+	filter := bson.D{{Key: "name", Value: "x"}}
+
+	writes, _, err := toWriteModels(context.TODO(), "db", "col", []WriteModel{
+		UpdateModelOf[versioned]{Filter: filter, Update: bson.D{{Key: "$set", Value: filter}}, Many: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.IsType(t, &mongo.UpdateManyModel{}, writes[0]) {
+		assert.Equal(t, bson.D{{Key: "$set", Value: filter}, {Key: "$inc", Value: bson.D{{Key: "_v", Value: 1}}}}, writes[0].(*mongo.UpdateManyModel).Update)
+	}
+}
@@ -0,0 +1,153 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ResultsFunc returns the results of several queries, in the order the queries were given.
+type ResultsFunc[T any] func(context.Context, *mongo.Client) (*[]T, error)
+
+// Any erases the result type of query, so queries with different results can be combined
+// with Sequence or Parallel.
+func Any[T any](query func(context.Context, *mongo.Client) (*T, error)) DocumentFunc[any] {
+	return func(ctx context.Context, c *mongo.Client) (*any, error) {
+		result, err := query(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+
+		var v any
+		if result != nil {
+			v = *result
+		}
+
+		return &v, nil
+	}
+}
+
+// Sequence runs queries one after the other, stopping at the first error. Results of queries
+// returning nil are the zero value of T.
+func Sequence[T any](queries ...func(context.Context, *mongo.Client) (*T, error)) ResultsFunc[T] {
+	return func(ctx context.Context, c *mongo.Client) (*[]T, error) {
+		results := make([]T, 0, len(queries))
+
+		for i, query := range queries {
+			result, err := query(ctx, c)
+			if err != nil {
+				return &results, fmt.Errorf("query %d: %w", i, err)
+			}
+
+			results = append(results, deref(result))
+		}
+
+		return &results, nil
+	}
+}
+
+// Parallel runs queries concurrently, at most limit at the same time, or all of them when
+// limit is not positive. The first error cancels the context of the rest and is returned.
+//
+// Sessions cannot be shared between goroutines, so it must not be used inside a transaction.
+func Parallel[T any](limit int, queries ...func(context.Context, *mongo.Client) (*T, error)) ResultsFunc[T] {
+	return func(ctx context.Context, c *mongo.Client) (*[]T, error) {
+		if limit <= 0 || limit > len(queries) {
+			limit = len(queries)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var (
+			results  = make([]T, len(queries))
+			sem      = make(chan struct{}, limit)
+			wg       sync.WaitGroup
+			errOnce  sync.Once
+			firstErr error
+		)
+
+		fail := func(err error) {
+			errOnce.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}
+
+	loop:
+		for i, query := range queries {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				fail(ctx.Err())
+				break loop
+			}
+
+			wg.Add(1)
+			go func(i int, query func(context.Context, *mongo.Client) (*T, error)) {
+				defer func() { <-sem; wg.Done() }()
+
+				result, err := query(ctx, c)
+				if err != nil {
+					fail(fmt.Errorf("query %d: %w", i, err))
+					return
+				}
+
+				results[i] = deref(result)
+			}(i, query)
+		}
+
+		wg.Wait()
+
+		if firstErr != nil {
+			return nil, firstErr
+		}
+
+		return &results, nil
+	}
+}
+
+// Map transforms the result of query with fn. A nil result is passed as the zero value of T.
+func Map[T, R any](query func(context.Context, *mongo.Client) (*T, error), fn func(T) (R, error)) DocumentFunc[R] {
+	return func(ctx context.Context, c *mongo.Client) (*R, error) {
+		result, err := query(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+
+		mapped, err := fn(deref(result))
+		if err != nil {
+			return nil, err
+		}
+
+		return &mapped, nil
+	}
+}
+
+// Fallback runs primary and, when it fails, alternative. It does not fall back once ctx is
+// done. If both fail the error of alternative is returned, mentioning the one of primary.
+func Fallback[T any](primary, alternative func(context.Context, *mongo.Client) (*T, error)) DocumentFunc[T] {
+	return func(ctx context.Context, c *mongo.Client) (*T, error) {
+		result, err := primary(ctx, c)
+		if err == nil || ctx.Err() != nil {
+			return result, err
+		}
+
+		result, altErr := alternative(ctx, c)
+		if altErr != nil {
+			return nil, fmt.Errorf("%w (primary failed: %v)", altErr, err)
+		}
+
+		return result, nil
+	}
+}
+
+func deref[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
@@ -0,0 +1,102 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func constQuery(n int64) CountFunc {
+	return func(context.Context, *mongo.Client) (*int64, error) { return &n, nil }
+}
+
+func failingQuery(err error) CountFunc {
+	return func(context.Context, *mongo.Client) (*int64, error) { return nil, err }
+}
+
+func TestSequence(t *testing.T) {
+	errBoom := errors.New("boom")
+	ran := false
+
+	results, err := Sequence(constQuery(1), constQuery(2))(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, *results)
+
+	results, err = Sequence(constQuery(1), failingQuery(errBoom), func(context.Context, *mongo.Client) (*int64, error) {
+		ran = true
+		return nil, nil
+	})(context.Background(), nil)
+	assert.ErrorIs(t, err, errBoom)
+	assert.Equal(t, []int64{1}, *results, "results of the queries run are kept")
+	assert.False(t, ran, "queries after the failed one are not run")
+
+	mixed, err := Sequence(Any(constQuery(1)), Any(DocumentFunc[string](func(context.Context, *mongo.Client) (*string, error) {
+		s := "x"
+		return &s, nil
+	})))(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{int64(1), "x"}, *mixed)
+}
+
+func TestParallel(t *testing.T) {
+	var running, maxRunning int32
+
+	queries := make([]func(context.Context, *mongo.Client) (*int64, error), 10)
+	for i := range queries {
+		i := int64(i)
+		queries[i] = func(context.Context, *mongo.Client) (*int64, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return &i, nil
+		}
+	}
+
+	results, err := Parallel(3, queries...)(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, *results, "results keep the order of the queries")
+	assert.LessOrEqual(t, maxRunning, int32(3))
+
+	errBoom := errors.New("boom")
+	cancelled := make(chan struct{})
+	_, err = Parallel(0, failingQuery(errBoom), func(ctx context.Context, _ *mongo.Client) (*int64, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})(context.Background(), nil)
+	assert.ErrorIs(t, err, errBoom)
+	<-cancelled
+}
+
+func TestMapAndFallback(t *testing.T) {
+	str, err := Map(constQuery(7), func(n int64) (string, error) { return strconv.FormatInt(n, 10), nil })(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "7", *str)
+
+	errPrimary, errAlternative := errors.New("primary"), errors.New("alternative")
+
+	n, err := Fallback(failingQuery(errPrimary), constQuery(2))(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), *n)
+
+	_, err = Fallback(failingQuery(errPrimary), failingQuery(errAlternative))(context.Background(), nil)
+	assert.ErrorIs(t, err, errAlternative)
+	assert.ErrorContains(t, err, "primary")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Fallback(failingQuery(context.Canceled), constQuery(2))(ctx, nil)
+	assert.ErrorIs(t, err, context.Canceled, "no fallback once the context is done")
+}
@@ -0,0 +1,544 @@
+package mongodb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultURI is the uri used when no other source sets one.
+	DefaultURI = "mongodb://localhost:27017"
+
+	// ConfigFileEnv is the environment variable pointing to a JSON config file.
+	ConfigFileEnv = "MONGO_CONFIG_FILE"
+)
+
+// Environment variables read by ClientConfigFromEnv.
+const (
+	EnvURI                    = "MONGO_URI"
+	EnvDatabase               = "MONGO_DATABASE"
+	EnvUsername               = "MONGO_USERNAME"
+	EnvPassword               = "MONGO_PASSWORD"
+	EnvAuthMechanism          = "MONGO_AUTH_MECHANISM"
+	EnvAuthSource             = "MONGO_AUTH_SOURCE"
+	EnvAppName                = "MONGO_APP_NAME"
+	EnvMinPoolSize            = "MONGO_MIN_POOL_SIZE"
+	EnvMaxPoolSize            = "MONGO_MAX_POOL_SIZE"
+	EnvConnectTimeout         = "MONGO_CONNECT_TIMEOUT"
+	EnvServerSelectionTimeout = "MONGO_SERVER_SELECTION_TIMEOUT"
+	EnvSocketTimeout          = "MONGO_SOCKET_TIMEOUT"
+	EnvCompressors            = "MONGO_COMPRESSORS"
+	EnvTLSCAFile              = "MONGO_TLS_CA_FILE"
+	EnvTLSCertificateKeyFile  = "MONGO_TLS_CERTIFICATE_KEY_FILE"
+	EnvTLSInsecure            = "MONGO_TLS_INSECURE"
+	EnvHealthCheckInterval    = "MONGO_HEALTH_CHECK_INTERVAL"
+	EnvFailFast               = "MONGO_FAIL_FAST"
+)
+
+// ClientConfig holds everything needed to build a *mongo.Client. Zero values mean
+// "not set", so the driver defaults apply.
+type ClientConfig struct {
+	URI string
+	// Database is the default database of the stores built with this config.
+	Database string
+
+	Username      string
+	Password      string
+	AuthMechanism string
+	AuthSource    string
+	AppName       string
+
+	MinPoolSize uint64
+	MaxPoolSize uint64
+
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	SocketTimeout          time.Duration
+
+	Compressors []string
+
+	TLSCAFile             string
+	TLSCertificateKeyFile string
+	TLSInsecure           bool
+
+	// ConnectRetry is the backoff used while the first connection fails.
+	ConnectRetry Backoff
+	// HealthCheckInterval enables a background ping every interval, 0 disables it.
+	HealthCheckInterval time.Duration
+	// FailFast makes DialConnection return ErrServerUnavailable instead of waiting
+	// while the health checker cannot reach the server.
+	FailFast bool
+
+	// explicit records the booleans set by the source of the config, even to false, so
+	// Merge can tell them from the ones left unset.
+	explicit explicitBools
+}
+
+type explicitBools struct {
+	tlsInsecure bool
+	failFast    bool
+}
+
+// ClientOption modifies a ClientConfig.
+type ClientOption func(*ClientConfig)
+
+// WithURI sets the connection string.
+func WithURI(uri string) ClientOption {
+	return func(c *ClientConfig) { c.URI = uri }
+}
+
+// WithDatabase sets the default database used by stores.
+func WithDatabase(database string) ClientOption {
+	return func(c *ClientConfig) { c.Database = database }
+}
+
+// WithCredentials sets the username and password used to authenticate.
+func WithCredentials(username, password string) ClientOption {
+	return func(c *ClientConfig) {
+		c.Username = username
+		c.Password = password
+	}
+}
+
+// WithAuth sets the auth mechanism (SCRAM-SHA-256, MONGODB-X509, ...) and the auth source database.
+func WithAuth(mechanism, source string) ClientOption {
+	return func(c *ClientConfig) {
+		c.AuthMechanism = mechanism
+		c.AuthSource = source
+	}
+}
+
+// WithAppName sets the application name reported to the server.
+func WithAppName(name string) ClientOption {
+	return func(c *ClientConfig) { c.AppName = name }
+}
+
+// WithPoolSize sets the minimum and maximum size of the connection pool.
+func WithPoolSize(min, max uint64) ClientOption {
+	return func(c *ClientConfig) {
+		c.MinPoolSize = min
+		c.MaxPoolSize = max
+	}
+}
+
+// WithTimeouts sets the connect, server selection and socket timeouts.
+func WithTimeouts(connect, serverSelection, socket time.Duration) ClientOption {
+	return func(c *ClientConfig) {
+		c.ConnectTimeout = connect
+		c.ServerSelectionTimeout = serverSelection
+		c.SocketTimeout = socket
+	}
+}
+
+// WithCompressors sets the wire compressors, like snappy, zlib or zstd.
+func WithCompressors(compressors ...string) ClientOption {
+	return func(c *ClientConfig) { c.Compressors = compressors }
+}
+
+// WithTLS sets the CA file and the combined certificate/key file used for TLS.
+func WithTLS(caFile, certificateKeyFile string) ClientOption {
+	return func(c *ClientConfig) {
+		c.TLSCAFile = caFile
+		c.TLSCertificateKeyFile = certificateKeyFile
+	}
+}
+
+// WithTLSInsecure disables the verification of the server certificate and host name. Only
+// meant for tests and development.
+func WithTLSInsecure(insecure bool) ClientOption {
+	return func(c *ClientConfig) { c.TLSInsecure = insecure }
+}
+
+// WithConnectRetry sets the backoff used to retry the first connection.
+func WithConnectRetry(b Backoff) ClientOption {
+	return func(c *ClientConfig) { c.ConnectRetry = b }
+}
+
+// WithHealthCheck pings the server every interval. If failFast is true, queries fail
+// immediately while the server is unreachable instead of waiting for it.
+func WithHealthCheck(interval time.Duration, failFast bool) ClientOption {
+	return func(c *ClientConfig) {
+		c.HealthCheckInterval = interval
+		c.FailFast = failFast
+	}
+}
+
+// DefaultClientConfig returns the config used when nothing else is set.
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{URI: DefaultURI}
+}
+
+// LoadClientConfig builds a config merging, from lowest to highest priority, the defaults,
+// the file pointed by MONGO_CONFIG_FILE, the environment variables and the options passed.
+func LoadClientConfig(opts ...ClientOption) (ClientConfig, error) {
+	cfg := DefaultClientConfig()
+
+	if path, ok := os.LookupEnv(ConfigFileEnv); ok && path != "" {
+		fileCfg, err := ClientConfigFromFile(path)
+		if err != nil {
+			return ClientConfig{}, err
+		}
+		cfg = cfg.Merge(fileCfg)
+	}
+
+	envCfg, err := ClientConfigFromEnv(os.LookupEnv)
+	if err != nil {
+		return ClientConfig{}, err
+	}
+	cfg = cfg.Merge(envCfg)
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg, nil
+}
+
+// ClientConfigFromEnv reads the config using lookup, which is usually os.LookupEnv.
+func ClientConfigFromEnv(lookup func(string) (string, bool)) (ClientConfig, error) {
+	var (
+		cfg ClientConfig
+		err error
+	)
+
+	get := func(key string) string {
+		v, _ := lookup(key)
+		return strings.TrimSpace(v)
+	}
+
+	cfg.URI = get(EnvURI)
+	cfg.Database = get(EnvDatabase)
+	cfg.Username = get(EnvUsername)
+	cfg.Password = get(EnvPassword)
+	cfg.AuthMechanism = get(EnvAuthMechanism)
+	cfg.AuthSource = get(EnvAuthSource)
+	cfg.AppName = get(EnvAppName)
+	cfg.TLSCAFile = get(EnvTLSCAFile)
+	cfg.TLSCertificateKeyFile = get(EnvTLSCertificateKeyFile)
+	cfg.Compressors = splitList(get(EnvCompressors))
+
+	if cfg.MinPoolSize, err = parseUint(EnvMinPoolSize, get(EnvMinPoolSize)); err != nil {
+		return ClientConfig{}, err
+	}
+	if cfg.MaxPoolSize, err = parseUint(EnvMaxPoolSize, get(EnvMaxPoolSize)); err != nil {
+		return ClientConfig{}, err
+	}
+	if cfg.ConnectTimeout, err = parseDuration(EnvConnectTimeout, get(EnvConnectTimeout)); err != nil {
+		return ClientConfig{}, err
+	}
+	if cfg.ServerSelectionTimeout, err = parseDuration(EnvServerSelectionTimeout, get(EnvServerSelectionTimeout)); err != nil {
+		return ClientConfig{}, err
+	}
+	if cfg.SocketTimeout, err = parseDuration(EnvSocketTimeout, get(EnvSocketTimeout)); err != nil {
+		return ClientConfig{}, err
+	}
+	if cfg.HealthCheckInterval, err = parseDuration(EnvHealthCheckInterval, get(EnvHealthCheckInterval)); err != nil {
+		return ClientConfig{}, err
+	}
+
+	tlsInsecure, err := parseBool(EnvTLSInsecure, get(EnvTLSInsecure))
+	if err != nil {
+		return ClientConfig{}, err
+	}
+	failFast, err := parseBool(EnvFailFast, get(EnvFailFast))
+	if err != nil {
+		return ClientConfig{}, err
+	}
+	cfg.setBools(tlsInsecure, failFast)
+
+	return cfg, nil
+}
+
+// fileConfig is the JSON representation of ClientConfig. Durations are written as
+// strings accepted by time.ParseDuration, like "5s" or "1m30s".
+type fileConfig struct {
+	URI                    string   `json:"uri"`
+	Database               string   `json:"database"`
+	Username               string   `json:"username"`
+	Password               string   `json:"password"`
+	AuthMechanism          string   `json:"auth_mechanism"`
+	AuthSource             string   `json:"auth_source"`
+	AppName                string   `json:"app_name"`
+	MinPoolSize            uint64   `json:"min_pool_size"`
+	MaxPoolSize            uint64   `json:"max_pool_size"`
+	ConnectTimeout         string   `json:"connect_timeout"`
+	ServerSelectionTimeout string   `json:"server_selection_timeout"`
+	SocketTimeout          string   `json:"socket_timeout"`
+	Compressors            []string `json:"compressors"`
+	TLSCAFile              string   `json:"tls_ca_file"`
+	TLSCertificateKeyFile  string   `json:"tls_certificate_key_file"`
+	TLSInsecure            *bool    `json:"tls_insecure"`
+	HealthCheckInterval    string   `json:"health_check_interval"`
+	FailFast               *bool    `json:"fail_fast"`
+}
+
+// ClientConfigFromFile reads the config from a JSON file.
+func ClientConfigFromFile(path string) (ClientConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ClientConfig{}, err
+	}
+
+	var fc fileConfig
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return ClientConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	cfg := ClientConfig{
+		URI:                   fc.URI,
+		Database:              fc.Database,
+		Username:              fc.Username,
+		Password:              fc.Password,
+		AuthMechanism:         fc.AuthMechanism,
+		AuthSource:            fc.AuthSource,
+		AppName:               fc.AppName,
+		MinPoolSize:           fc.MinPoolSize,
+		MaxPoolSize:           fc.MaxPoolSize,
+		Compressors:           fc.Compressors,
+		TLSCAFile:             fc.TLSCAFile,
+		TLSCertificateKeyFile: fc.TLSCertificateKeyFile,
+	}
+	cfg.setBools(fc.TLSInsecure, fc.FailFast)
+
+	if cfg.ConnectTimeout, err = parseDuration("connect_timeout", fc.ConnectTimeout); err != nil {
+		return ClientConfig{}, err
+	}
+	if cfg.ServerSelectionTimeout, err = parseDuration("server_selection_timeout", fc.ServerSelectionTimeout); err != nil {
+		return ClientConfig{}, err
+	}
+	if cfg.SocketTimeout, err = parseDuration("socket_timeout", fc.SocketTimeout); err != nil {
+		return ClientConfig{}, err
+	}
+	if cfg.HealthCheckInterval, err = parseDuration("health_check_interval", fc.HealthCheckInterval); err != nil {
+		return ClientConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// setBools sets the booleans read from a source, nil when the source does not have them.
+func (c *ClientConfig) setBools(tlsInsecure, failFast *bool) {
+	if tlsInsecure != nil {
+		c.TLSInsecure, c.explicit.tlsInsecure = *tlsInsecure, true
+	}
+	if failFast != nil {
+		c.FailFast, c.explicit.failFast = *failFast, true
+	}
+}
+
+// Merge returns a copy of c where every field set in other overrides the one in c. Booleans
+// are set when true, or when false was read explicitly by ClientConfigFromEnv or
+// ClientConfigFromFile, so MONGO_TLS_INSECURE=false turns off the tls_insecure of a file.
+func (c ClientConfig) Merge(other ClientConfig) ClientConfig {
+	if other.URI != "" {
+		c.URI = other.URI
+	}
+	if other.Database != "" {
+		c.Database = other.Database
+	}
+	if other.Username != "" {
+		c.Username = other.Username
+	}
+	if other.Password != "" {
+		c.Password = other.Password
+	}
+	if other.AuthMechanism != "" {
+		c.AuthMechanism = other.AuthMechanism
+	}
+	if other.AuthSource != "" {
+		c.AuthSource = other.AuthSource
+	}
+	if other.AppName != "" {
+		c.AppName = other.AppName
+	}
+	if other.MinPoolSize != 0 {
+		c.MinPoolSize = other.MinPoolSize
+	}
+	if other.MaxPoolSize != 0 {
+		c.MaxPoolSize = other.MaxPoolSize
+	}
+	if other.ConnectTimeout != 0 {
+		c.ConnectTimeout = other.ConnectTimeout
+	}
+	if other.ServerSelectionTimeout != 0 {
+		c.ServerSelectionTimeout = other.ServerSelectionTimeout
+	}
+	if other.SocketTimeout != 0 {
+		c.SocketTimeout = other.SocketTimeout
+	}
+	if len(other.Compressors) != 0 {
+		c.Compressors = other.Compressors
+	}
+	if other.TLSCAFile != "" {
+		c.TLSCAFile = other.TLSCAFile
+	}
+	if other.TLSCertificateKeyFile != "" {
+		c.TLSCertificateKeyFile = other.TLSCertificateKeyFile
+	}
+	if other.TLSInsecure || other.explicit.tlsInsecure {
+		c.TLSInsecure = other.TLSInsecure
+		c.explicit.tlsInsecure = true
+	}
+	if other.ConnectRetry != (Backoff{}) {
+		c.ConnectRetry = other.ConnectRetry
+	}
+	if other.HealthCheckInterval != 0 {
+		c.HealthCheckInterval = other.HealthCheckInterval
+	}
+	if other.FailFast || other.explicit.failFast {
+		c.FailFast = other.FailFast
+		c.explicit.failFast = true
+	}
+
+	return c
+}
+
+// ClientOptions translates the config to the driver options.
+func (c ClientConfig) ClientOptions() (*options.ClientOptions, error) {
+	uri := c.URI
+	if uri == "" {
+		uri = DefaultURI
+	}
+
+	opt := options.Client().ApplyURI(uri)
+
+	if c.Username != "" || c.AuthMechanism != "" {
+		opt.SetAuth(options.Credential{
+			AuthMechanism: c.AuthMechanism,
+			AuthSource:    c.AuthSource,
+			Username:      c.Username,
+			Password:      c.Password,
+			PasswordSet:   c.Password != "",
+		})
+	}
+	if c.AppName != "" {
+		opt.SetAppName(c.AppName)
+	}
+	if c.MinPoolSize != 0 {
+		opt.SetMinPoolSize(c.MinPoolSize)
+	}
+	if c.MaxPoolSize != 0 {
+		opt.SetMaxPoolSize(c.MaxPoolSize)
+	}
+	if c.ConnectTimeout != 0 {
+		opt.SetConnectTimeout(c.ConnectTimeout)
+	}
+	if c.ServerSelectionTimeout != 0 {
+		opt.SetServerSelectionTimeout(c.ServerSelectionTimeout)
+	}
+	if c.SocketTimeout != 0 {
+		opt.SetSocketTimeout(c.SocketTimeout)
+	}
+	if len(c.Compressors) != 0 {
+		opt.SetCompressors(c.Compressors)
+	}
+
+	if c.TLSCAFile != "" || c.TLSCertificateKeyFile != "" || c.TLSInsecure {
+		tlsCfg, err := c.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		opt.SetTLSConfig(tlsCfg)
+	}
+
+	return opt, opt.Validate()
+}
+
+func (c ClientConfig) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: c.TLSInsecure}
+
+	if c.TLSCAFile != "" {
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found", c.TLSCAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if c.TLSCertificateKeyFile != "" {
+		pem, err := os.ReadFile(c.TLSCertificateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		// The file contains both the certificate and the private key, as mongod expects.
+		cert, err := tls.X509KeyPair(pem, pem)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.TLSCertificateKeyFile, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	var result []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+func parseUint(key, value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	u, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return u, nil
+}
+
+// parseBool returns nil when value is empty, so unset variables can be told from false ones.
+func parseBool(key, value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return &b, nil
+}
+
+func parseDuration(key, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s: negative duration %q", key, value)
+	}
+
+	return d, nil
+}
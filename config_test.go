@@ -0,0 +1,210 @@
+package mongodb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientConfigFromEnv(t *testing.T) {
+	var testCases = []struct {
+		description string
+		env         map[string]string
+		want        ClientConfig
+		wantErr     bool
+	}{
+		{
+			description: "empty environment returns an empty config",
+			env:         map[string]string{},
+			want:        ClientConfig{},
+		},
+		{
+			description: "every variable is parsed",
+			env: map[string]string{
+				EnvURI:                    "mongodb://staging:27017",
+				EnvDatabase:               "orders",
+				EnvUsername:               "user",
+				EnvPassword:               "secret",
+				EnvAuthMechanism:          "SCRAM-SHA-256",
+				EnvAuthSource:             "admin",
+				EnvAppName:                "orders",
+				EnvMinPoolSize:            "2",
+				EnvMaxPoolSize:            "50",
+				EnvConnectTimeout:         "3s",
+				EnvServerSelectionTimeout: "10s",
+				EnvSocketTimeout:          "1m",
+				EnvCompressors:            "snappy, zstd",
+				EnvTLSCAFile:              "/etc/ssl/ca.pem",
+				EnvTLSCertificateKeyFile:  "/etc/ssl/client.pem",
+				EnvTLSInsecure:            "true",
+				EnvHealthCheckInterval:    "30s",
+				EnvFailFast:               "1",
+			},
+			want: ClientConfig{
+				URI:                    "mongodb://staging:27017",
+				Database:               "orders",
+				Username:               "user",
+				Password:               "secret",
+				AuthMechanism:          "SCRAM-SHA-256",
+				AuthSource:             "admin",
+				AppName:                "orders",
+				MinPoolSize:            2,
+				MaxPoolSize:            50,
+				ConnectTimeout:         3 * time.Second,
+				ServerSelectionTimeout: 10 * time.Second,
+				SocketTimeout:          time.Minute,
+				Compressors:            []string{"snappy", "zstd"},
+				TLSCAFile:              "/etc/ssl/ca.pem",
+				TLSCertificateKeyFile:  "/etc/ssl/client.pem",
+				TLSInsecure:            true,
+				HealthCheckInterval:    30 * time.Second,
+				FailFast:               true,
+				explicit:               explicitBools{tlsInsecure: true, failFast: true},
+			},
+		},
+		{
+			description: "invalid pool size returns an error",
+			env:         map[string]string{EnvMaxPoolSize: "many"},
+			wantErr:     true,
+		},
+		{
+			description: "invalid duration returns an error",
+			env:         map[string]string{EnvConnectTimeout: "5 seconds"},
+			wantErr:     true,
+		},
+		{
+			description: "invalid bool returns an error",
+			env:         map[string]string{EnvTLSInsecure: "maybe"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.description, func(t *testing.T) {
+			got, err := ClientConfigFromEnv(func(key string) (string, bool) {
+				v, ok := tCase.env[key]
+				return v, ok
+			})
+
+			if tCase.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tCase.want, got)
+		})
+	}
+}
+
+func TestClientConfigFromFile(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"uri": "mongodb://production:27017",
+		"username": "reader",
+		"password": "abc",
+		"auth_source": "admin",
+		"max_pool_size": 100,
+		"connect_timeout": "2s",
+		"compressors": ["zlib"]
+	}`)
+
+	got, err := ClientConfigFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, ClientConfig{
+		URI:            "mongodb://production:27017",
+		Username:       "reader",
+		Password:       "abc",
+		AuthSource:     "admin",
+		MaxPoolSize:    100,
+		ConnectTimeout: 2 * time.Second,
+		Compressors:    []string{"zlib"},
+	}, got)
+
+	_, err = ClientConfigFromFile(writeConfigFile(t, `{"socket_timeout": "forever"}`))
+	assert.Error(t, err)
+}
+
+func TestLoadClientConfigMergesSources(t *testing.T) {
+	path := writeConfigFile(t, `{"uri": "mongodb://from-file:27017", "app_name": "file", "max_pool_size": 10}`)
+
+	t.Setenv(ConfigFileEnv, path)
+	t.Setenv(EnvAppName, "env")
+	t.Setenv(EnvMinPoolSize, "1")
+
+	got, err := LoadClientConfig(WithCredentials("opt", "pass"), WithPoolSize(5, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "mongodb://from-file:27017", got.URI, "file overrides defaults")
+	assert.Equal(t, "env", got.AppName, "env overrides file")
+	assert.Equal(t, "opt", got.Username, "options override everything")
+	assert.Equal(t, uint64(5), got.MinPoolSize, "options override env")
+	assert.Equal(t, uint64(20), got.MaxPoolSize, "options override file")
+}
+
+func TestLoadClientConfigExplicitFalse(t *testing.T) {
+	path := writeConfigFile(t, `{"tls_insecure": true, "fail_fast": true}`)
+
+	t.Setenv(ConfigFileEnv, path)
+	t.Setenv(EnvTLSInsecure, "false")
+
+	got, err := LoadClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.False(t, got.TLSInsecure, "an explicit false in the env overrides the file")
+	assert.True(t, got.FailFast, "unset variables keep the value of the file")
+
+	got, err = LoadClientConfig(WithTLSInsecure(true), WithHealthCheck(0, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, got.TLSInsecure, "options override env")
+	assert.False(t, got.FailFast, "options override file")
+}
+
+func TestClientConfigClientOptions(t *testing.T) {
+	cfg := ClientConfig{
+		URI:           "mongodb://localhost:27017",
+		Username:      "admin",
+		Password:      "abc123.",
+		AuthMechanism: "SCRAM-SHA-256",
+		AppName:       "test",
+		MaxPoolSize:   30,
+		SocketTimeout: time.Second,
+		Compressors:   []string{"zstd"},
+	}
+
+	opt, err := cfg.ClientOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "admin", opt.Auth.Username)
+	assert.Equal(t, "SCRAM-SHA-256", opt.Auth.AuthMechanism)
+	assert.Equal(t, "test", *opt.AppName)
+	assert.Equal(t, uint64(30), *opt.MaxPoolSize)
+	assert.Equal(t, time.Second, *opt.SocketTimeout)
+	assert.Equal(t, []string{"zstd"}, opt.Compressors)
+
+	_, err = ClientConfig{TLSCAFile: filepath.Join(t.TempDir(), "missing.pem")}.ClientOptions()
+	assert.Error(t, err)
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "mongo.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
@@ -2,53 +2,69 @@ package mongodb
 
 import (
 	"context"
-	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-var (
-	client     *mongo.Client
-	clientErr  error
-	clientOnce sync.Once
-)
+const defaultConnectTimeout = 5 * time.Second
 
-func GetMongoClient() (*mongo.Client, error) {
-	clientOnce.Do(func() {
-		ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
-		defer cl()
+// Configure sets the config used by GetMongoClient. It has to be called before the first
+// connection is made.
+func Configure(cfg ClientConfig) error {
+	return RegisterClient(DefaultClientName, cfg)
+}
 
-		opt := options.Client().
-			ApplyURI("mongodb://localhost:27017").
-			SetAuth(options.Credential{Username: "admin", Password: "abc123."})
+// NewClient connects to the server described by cfg and checks it is reachable.
+func NewClient(ctx context.Context, cfg ClientConfig) (*mongo.Client, error) {
+	opt, err := cfg.ClientOptions()
+	if err != nil {
+		return nil, err
+	}
 
-		cli, err := mongo.Connect(ctx, opt)
-		if err != nil {
-			clientErr = err
-			return
-		}
+	timeout := cfg.ConnectTimeout
+	if timeout == 0 {
+		timeout = defaultConnectTimeout
+	}
 
-		if err := cli.Ping(ctx, readpref.Primary()); err != nil {
-			clientErr = err
-			return
-		}
+	ctx, cl := context.WithTimeout(ctx, timeout)
+	defer cl()
 
-		client = cli
-	})
+	cli, err := mongo.Connect(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
 
-	return client, clientErr
+	if err := cli.Ping(ctx, readpref.Primary()); err != nil {
+		_ = cli.Disconnect(context.Background())
+		return nil, err
+	}
+
+	return cli, nil
+}
+
+func GetMongoClient() (*mongo.Client, error) {
+	return defaultRegistry.Client(context.TODO(), DefaultClientName)
 }
 
 func DialConnection[T any](ctx context.Context, query func(context.Context, *mongo.Client) (*T, error)) (*T, error) {
-	cli, err := GetMongoClient()
+	return DialConnectionTo(ctx, DefaultClientName, query)
+}
+
+// DialConnectionTo is like DialConnection but runs the query using the client registered under name.
+func DialConnectionTo[T any](ctx context.Context, name string, query func(context.Context, *mongo.Client) (*T, error)) (*T, error) {
+	if err := defaultRegistry.acquire(); err != nil {
+		return nil, err
+	}
+	defer defaultRegistry.release()
+
+	store, err := defaultRegistry.Store(ctx, name)
 	if err != nil {
 		return nil, err
 	}
 
-	result, err := query(ctx, cli)
+	result, err := Run(ctx, store, query)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,81 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DoCount counts the documents matching filter. A nil filter counts every document, prefer
+// DoEstimatedCount for that on big collections.
+func DoCount(db, col string, filter any, opts ...*options.CountOptions) CountFunc {
+	return Instrument(Operation{Name: "count", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*int64, error) {
+		count, err := c.Database(db).Collection(col).CountDocuments(ctx, visibleFilter(ctx, db, col, orEmptyFilter(filter)), opts...)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		return &count, nil
+	})
+}
+
+// DoEstimatedCount returns the number of documents of col from its metadata, without scanning
+// it. The count may be off after an unclean shutdown or while there are orphaned documents
+// in a sharded cluster.
+func DoEstimatedCount(db, col string) CountFunc {
+	return Instrument(Operation{Name: "estimatedCount", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*int64, error) {
+		count, err := c.Database(db).Collection(col).EstimatedDocumentCount(ctx)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		return &count, nil
+	})
+}
+
+// DoDistinct returns the distinct values of field among the documents matching filter,
+// decoded as V. Documents where field is an array contribute each of its elements.
+func DoDistinct[V any](db, col, field string, filter any) FindAllFunc[V] {
+	return Instrument(Operation{Name: "distinct", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*[]V, error) {
+		values, err := c.Database(db).Collection(col).Distinct(ctx, field, visibleFilter(ctx, db, col, orEmptyFilter(filter)))
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result, err := decodeValues[V](values)
+		if err != nil {
+			return nil, err
+		}
+
+		return &result, nil
+	})
+}
+
+// decodeValues converts the values decoded by the driver into V, going through their bson
+// encoding so the usual rules apply, like int32 into int.
+func decodeValues[V any](values []any) ([]V, error) {
+	result := make([]V, len(values))
+
+	for i, value := range values {
+		t, data, err := bson.MarshalValue(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := (bson.RawValue{Type: t, Value: data}).Unmarshal(&result[i]); err != nil {
+			return nil, fmt.Errorf("value %d: %w", i, err)
+		}
+	}
+
+	return result, nil
+}
+
+func orEmptyFilter(filter any) any {
+	if filter == nil {
+		return bson.D{}
+	}
+	return filter
+}
@@ -0,0 +1,30 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDecodeValues(t *testing.T) {
+	ints, err := decodeValues[int]([]any{int32(1), int64(2), 3.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{1, 2, 3}, ints)
+
+	docs, err := decodeValues[Person]([]any{bson.D{{Key: "name", Value: "Ana"}, {Key: "age", Value: int32(30)}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []Person{{Name: "Ana", Age: 30}}, docs)
+
+	_, err = decodeValues[int]([]any{"one"})
+	assert.Error(t, err)
+}
+
+func TestOrEmptyFilter(t *testing.T) {
+	assert.Equal(t, bson.D{}, orEmptyFilter(nil))
+	assert.Equal(t, bson.M{"a": 1}, orEmptyFilter(bson.M{"a": 1}))
+}
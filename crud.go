@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"context"
+	"fmt"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -18,14 +19,19 @@ type FindOneFunc func(context.Context, *mongo.Client) (*mongo.SingleResult, erro
 type DeleteByObjectIDs func(context.Context, *mongo.Client) (deleted *int64, err error)
 
 func DoInsert[T any](db, col string, arr []T) InsertManyResultFunc {
-	return func(ctx context.Context, c *mongo.Client) (*mongo.InsertManyResult, error) {
+	return Instrument(Operation{Name: "insert", Database: db, Collection: col}, func(ctx context.Context, c *mongo.Client) (*mongo.InsertManyResult, error) {
 		input := make([]interface{}, len(arr))
 		for i := 0; i < len(arr); i++ {
-			input[i] = arr[i]
+			doc := stampInsert(arr[i])
+			if err := beforeInsert(ctx, &doc); err != nil {
+				return nil, fmt.Errorf("document %d: %w", i, err)
+			}
+			input[i] = doc
 		}
 
-		return c.Database(db).Collection(col).InsertMany(ctx, input)
-	}
+		imr, err := c.Database(db).Collection(col).InsertMany(ctx, input)
+		return imr, translateError(err)
+	})
 }
 
 func DoInsertOne[T any](db, col string, input T) InsertManyResultFunc {
@@ -33,26 +39,46 @@ func DoInsertOne[T any](db, col string, input T) InsertManyResultFunc {
 }
 
 func DoFind[T any](db, col string, filter any, result *T, opts ...*options.FindOptions) FindFunc {
-	return func(ctx context.Context, c *mongo.Client) (*any, error) {
-		cursor, err := c.Database(db).Collection(col).Find(ctx, filter, opts...)
-		if err != nil {
-			return nil, err
-		}
+	return doFind(db, col, filter, result, opts...)
+}
 
-		err = cursor.All(ctx, result)
-		return nil, err
+// doFind is DoFind for a result only known at run time, like the one of Store.Find.
+func doFind(db, col string, filter, result any, opts ...*options.FindOptions) FindFunc {
+	return Instrument(Operation{Name: "find", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*any, error) {
+		return nil, find(ctx, c, db, col, filter, result, opts...)
+	})
+}
+
+func find(ctx context.Context, c *mongo.Client, db, col string, filter, result any, opts ...*options.FindOptions) error {
+	cursor, err := c.Database(db).Collection(col).Find(ctx, visibleFilter(ctx, db, col, filter), opts...)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if err := cursor.All(ctx, result); err != nil {
+		return translateError(err)
 	}
+
+	return afterFindResult(ctx, result)
 }
 
+// DoFindAndUpdate applies toUpdate to the first document matching filter, sent as it is after
+// its BeforeUpdate hook. It knows nothing of the documents, so their version and updated
+// timestamp are left alone: use DoFindOneAndUpdate for the documents having them.
 func DoFindAndUpdate[T any](db, col string, filter any, toUpdate T) FindOneFunc {
-	return func(ctx context.Context, c *mongo.Client) (*mongo.SingleResult, error) {
-		sr := c.Database(db).Collection(col).FindOneAndUpdate(ctx, filter, toUpdate)
-		return sr, sr.Err()
-	}
+	return Instrument(Operation{Name: "findAndUpdate", Database: db, Collection: col}, func(ctx context.Context, c *mongo.Client) (*mongo.SingleResult, error) {
+		update := toUpdate
+		if err := beforeUpdate(ctx, &update); err != nil {
+			return nil, err
+		}
+
+		sr := c.Database(db).Collection(col).FindOneAndUpdate(ctx, visibleFilter(ctx, db, col, filter), update)
+		return sr, translateError(sr.Err())
+	})
 }
 
 func DoDeleteByStringObjectID(db, col string, objectIDs ...string) DeleteByObjectIDs {
-	return func(ctx context.Context, c *mongo.Client) (*int64, error) {
+	return Instrument(Operation{Name: "delete", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*int64, error) {
 		var (
 			objectIDArr = make([]primitive.ObjectID, len(objectIDs))
 			err         error
@@ -61,21 +87,34 @@ func DoDeleteByStringObjectID(db, col string, objectIDs ...string) DeleteByObjec
 		for i := range objectIDs {
 			objectIDArr[i], err = primitive.ObjectIDFromHex(objectIDs[i])
 			if err != nil {
-				return nil, err
+				return nil, &InvalidObjectIDError{Index: i, Input: objectIDs[i], Err: err}
 			}
 		}
 
-		return DoDeleteByObjectID(db, col, objectIDArr...)(ctx, c)
-	}
+		return deleteByObjectID(ctx, c, db, col, objectIDArr)
+	})
 }
 
 func DoDeleteByObjectID[T any](db, col string, objectIDs ...T) DeleteByObjectIDs {
-	return func(ctx context.Context, c *mongo.Client) (*int64, error) {
-		dr, err := c.Database(db).Collection(col).DeleteMany(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: objectIDs}}}})
-		if dr == nil {
-			return nil, err
-		}
+	return Instrument(Operation{Name: "delete", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*int64, error) {
+		return deleteByObjectID(ctx, c, db, col, objectIDs)
+	})
+}
+
+func deleteByObjectID[T any](ctx context.Context, c *mongo.Client, db, col string, objectIDs []T) (*int64, error) {
+	var (
+		coll   = c.Database(db).Collection(col)
+		filter = bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: objectIDs}}}}
+	)
 
-		return &dr.DeletedCount, err
+	if softDeleteEnabled(db, col) {
+		return softDelete(ctx, coll, filter, true)
 	}
+
+	dr, err := coll.DeleteMany(ctx, filter)
+	if dr == nil {
+		return nil, translateError(err)
+	}
+
+	return &dr.DeletedCount, translateError(err)
 }
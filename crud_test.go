@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const (
@@ -285,3 +286,392 @@ func TestComplexFindQueries(t *testing.T) {
 		})
 	}
 }
+
+func TestFindAllAndFindOne(t *testing.T) {
+	var input = []Person{
+		{Name: "Ana", Surname: "Lopez", Age: 30},
+		{Name: "Bea", Surname: "Lopez", Age: 40},
+		{Name: "Carla", Surname: "Lopez", Age: 50},
+	}
+
+	ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cl()
+
+	insertDocuments(t, crudTestDb, crudTestCollection, input)
+
+	got, err := DialConnection(ctx, DoFindAll[Person](crudTestDb, crudTestCollection, o.F("surname", o.Eq("Lopez")), WithSort("-age"), WithLimit(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []Person{input[2], input[1]}, *got)
+
+	one, err := DialConnection(ctx, DoFindOne[Person](crudTestDb, crudTestCollection, o.F("name", o.Eq("Bea"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, input[1], *one)
+
+	_, err = DialConnection(ctx, DoFindOne[Person](crudTestDb, crudTestCollection, o.F("name", o.Eq("Nobody"))))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFindEachStopsEarly(t *testing.T) {
+	var input = []Person{
+		{Name: "Dani", Surname: "Streaming", Age: 1},
+		{Name: "Eva", Surname: "Streaming", Age: 2},
+		{Name: "Fran", Surname: "Streaming", Age: 3},
+	}
+
+	ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cl()
+
+	insertDocuments(t, crudTestDb, crudTestCollection, input)
+
+	var got []Person
+	count, err := DialConnection(ctx, DoFindEach(crudTestDb, crudTestCollection, o.F("surname", o.Eq("Streaming")), func(p Person) error {
+		got = append(got, p)
+		if len(got) == 2 {
+			return ErrStopIteration
+		}
+		return nil
+	}, WithSort("age"), WithBatchSize(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(2), *count)
+	assert.Equal(t, input[:2], got)
+}
+
+func TestInsertChunked(t *testing.T) {
+	var input = make([]Person, 25)
+	for i := range input {
+		input[i] = Person{Name: "Chunk", Surname: "Chunked", Age: i}
+	}
+
+	ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cl()
+
+	var progress []int
+	result, err := DialConnection(ctx, DoInsertChunked(crudTestDb, crudTestCollection, input, &InsertChunkedOptions{
+		ChunkSize:   10,
+		Concurrency: 2,
+		Progress:    func(done, total int) { progress = append(progress, done) },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+		defer cl()
+		DialConnection(ctx, DoDeleteByObjectID(crudTestDb, crudTestCollection, result.InsertedIDs...))
+	})
+
+	assert.Len(t, result.InsertedIDs, len(input))
+	assert.Equal(t, 25, progress[len(progress)-1])
+
+	got, err := DialConnection(ctx, DoFindAll[Person](crudTestDb, crudTestCollection, o.F("surname", o.Eq("Chunked")), WithSort("age")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, input, *got)
+}
+
+func TestCountAndDistinct(t *testing.T) {
+	var input = []Person{
+		{Name: "Gala", Surname: "Counted", Age: 20},
+		{Name: "Hugo", Surname: "Counted", Age: 20},
+		{Name: "Ines", Surname: "Counted", Age: 35},
+	}
+
+	ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cl()
+
+	insertDocuments(t, crudTestDb, crudTestCollection, input)
+
+	count, err := DialConnection(ctx, DoCount(crudTestDb, crudTestCollection, o.And(o.F("surname", o.Eq("Counted")), o.F("age", o.Gt(25)))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), *count)
+
+	estimated, err := DialConnection(ctx, DoEstimatedCount(crudTestDb, crudTestCollection))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.GreaterOrEqual(t, *estimated, int64(len(input)))
+
+	ages, err := DialConnection(ctx, DoDistinct[int](crudTestDb, crudTestCollection, "age", o.F("surname", o.Eq("Counted"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.ElementsMatch(t, []int{20, 35}, *ages)
+}
+
+func TestDeleteByFilter(t *testing.T) {
+	var input = []Person{
+		{Name: "Juan", Surname: "Deleted", Age: 1},
+		{Name: "Kai", Surname: "Deleted", Age: 2},
+		{Name: "Luz", Surname: "Deleted", Age: 3},
+	}
+
+	ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cl()
+
+	insertDocuments(t, crudTestDb, crudTestCollection, input)
+
+	filter := o.F("surname", o.Eq("Deleted"))
+
+	_, err := DialConnection(ctx, DoDeleteMany(crudTestDb, crudTestCollection, bson.D{}))
+	assert.ErrorIs(t, err, ErrEmptyFilter)
+
+	wouldDelete, err := DialConnection(ctx, DoDeleteMany(crudTestDb, crudTestCollection, filter, WithDryRun()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(3), *wouldDelete)
+
+	deleted, err := DialConnection(ctx, DoDeleteOne(crudTestDb, crudTestCollection, filter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), *deleted)
+
+	deleted, err = DialConnection(ctx, DoDeleteMany(crudTestDb, crudTestCollection, filter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), *deleted)
+}
+
+func TestSoftDelete(t *testing.T) {
+	const col = "soft_delete_test"
+
+	var input = []Person{
+		{Name: "Mar", Surname: "Soft", Age: 1},
+		{Name: "Noa", Surname: "Soft", Age: 2},
+	}
+
+	EnableSoftDelete(crudTestDb, col)
+	t.Cleanup(func() { DisableSoftDelete(crudTestDb, col) })
+
+	ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cl()
+
+	if _, err := DialConnection(ctx, DoInsert(crudTestDb, col, input)); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+		defer cl()
+		DisableSoftDelete(crudTestDb, col)
+		DialConnection(ctx, DoDeleteMany(crudTestDb, col, nil, WithAllowFullCollection()))
+	})
+
+	deleted, err := DialConnection(WithActor(ctx, "tester"), DoDeleteOne(crudTestDb, col, o.F("name", o.Eq("Mar"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), *deleted)
+
+	visible, err := DialConnection(ctx, DoFindAll[Person](crudTestDb, col, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []Person{input[1]}, *visible)
+
+	all, err := DialConnection(WithDeleted(ctx), DoCount(crudTestDb, col, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), *all)
+
+	trashed, err := DialConnection(OnlyDeleted(ctx), DoFindOne[bson.M](crudTestDb, col, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "tester", (*trashed)[DeletedByField])
+
+	ur, err := DialConnection(ctx, DoUpdateOne(crudTestDb, col, o.F("name", o.Eq("Mar")), o.F("$set", bson.E{Key: "age", Value: 10})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(0), ur.MatchedCount, "soft deleted documents are not updated")
+
+	_, err = DialConnection(ctx, DoFindAndUpdate(crudTestDb, col, o.F("name", o.Eq("Mar")), o.F("$set", bson.E{Key: "age", Value: 10})))
+	assert.ErrorIs(t, err, ErrNotFound, "soft deleted documents are not found and updated")
+
+	restored, err := DialConnection(ctx, DoRestore(crudTestDb, col, o.F("name", o.Eq("Mar"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), *restored)
+
+	count, err := DialConnection(ctx, DoCount(crudTestDb, col, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), *count)
+
+	removed, err := DialConnection(ctx, DoFindOneAndDelete[Person](crudTestDb, col, o.F("name", o.Eq("Noa")), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, input[1], *removed)
+
+	all, err = DialConnection(WithDeleted(ctx), DoCount(crudTestDb, col, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), *all, "DoFindOneAndDelete keeps the document")
+}
+
+type repositoryPerson struct {
+	ID   string `bson:"_id,omitempty"`
+	Name string `bson:"name"`
+	Age  int    `bson:"age"`
+}
+
+func TestRepository(t *testing.T) {
+	repo := NewRepository[repositoryPerson, string](crudTestDb, "repository_test")
+
+	ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cl()
+
+	ids, err := repo.CreateMany(ctx, []repositoryPerson{{Name: "Olga", Age: 20}, {Name: "Pau", Age: 30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+		defer cl()
+		DialConnection(ctx, DoDeleteMany(crudTestDb, "repository_test", nil, WithAllowFullCollection()))
+	})
+
+	got, err := repo.Get(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, repositoryPerson{ID: ids[0], Name: "Olga", Age: 20}, *got)
+
+	if err := repo.Update(ctx, ids[1], o.F("$inc", bson.E{Key: "age", Value: 1})); err != nil {
+		t.Fatal(err)
+	}
+
+	older, err := repo.Find(ctx, o.F("age", o.Gt(25)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []repositoryPerson{{ID: ids[1], Name: "Pau", Age: 31}}, older)
+
+	exists, err := repo.Exists(ctx, o.F("name", o.Eq("Olga")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, exists)
+
+	assert.NoError(t, repo.Delete(ctx, ids[0]))
+	assert.ErrorIs(t, repo.Delete(ctx, ids[0]), ErrNotFound)
+
+	count, err := repo.Count(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), count)
+}
+
+type versionedPerson struct {
+	ID      primitive.ObjectID `bson:"_id"`
+	Name    string             `bson:"name"`
+	Version int64              `bson:"_v" mongo:"version"`
+}
+
+func TestOptimisticLocking(t *testing.T) {
+	const col = "version_test"
+
+	ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cl()
+
+	doc := versionedPerson{ID: primitive.NewObjectID(), Name: "Quim"}
+	if _, err := DialConnection(ctx, DoInsertOne(crudTestDb, col, doc)); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+		defer cl()
+		DialConnection(ctx, DoDeleteByObjectID(crudTestDb, col, doc.ID))
+	})
+
+	filter := bson.D{{Key: "_id", Value: doc.ID}}
+
+	first := doc
+	first.Name = "Quim 1"
+	if _, err := DialConnection(ctx, DoReplaceOne(crudTestDb, col, filter, first)); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := doc
+	stale.Name = "Quim stale"
+	_, err := DialConnection(ctx, DoReplaceOne(crudTestDb, col, filter, stale))
+
+	var conflict *ConflictError
+	if assert.ErrorAs(t, err, &conflict) {
+		assert.Equal(t, int64(0), conflict.Expected)
+		assert.Equal(t, int64(1), conflict.Current)
+	}
+
+	updated, err := DialConnection(ctx, DoFindOneAndUpdate[versionedPerson](crudTestDb, col, filter, o.F("$set", bson.E{Key: "name", Value: "Quim 2"}), &FindAndModifyOptions{ReturnDocument: options.After}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), updated.Version)
+
+	if _, err := DialConnection(ctx, DoUpdateOneOf[versionedPerson](crudTestDb, col, filter, o.F("$set", bson.E{Key: "name", Value: "Quim 3"}), WithVersion(2))); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = DialConnection(ctx, DoUpdateOneOf[versionedPerson](crudTestDb, col, filter, o.F("$set", bson.E{Key: "name", Value: "Quim stale"}), WithVersion(2)))
+	if assert.ErrorAs(t, err, &conflict) {
+		assert.Equal(t, int64(3), conflict.Current)
+	}
+
+	stale = *updated
+	stale.Name = "Quim stale"
+	_, err = DialConnection(ctx, DoReplaceOne(crudTestDb, col, filter, stale))
+	assert.ErrorAs(t, err, &conflict, "the replace is stale after DoUpdateOneOf")
+
+	_, err = DialConnection(ctx, DoFindOneAndUpdate[versionedPerson](crudTestDb, col, filter, o.F("$set", bson.E{Key: "name", Value: "Quim stale"}), &FindAndModifyOptions{Upsert: true, Version: new(int64)}))
+	if assert.ErrorAs(t, err, &conflict, "an upsert with a stale version hits the _id index") {
+		assert.Equal(t, int64(3), conflict.Current)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	ctx, cl := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cl()
+
+	insertDocuments(t, crudTestDb, crudTestCollection, []Person{{Name: "Rita", Surname: "Explained"}})
+
+	result, err := DialConnection(ctx, DoExplain(crudTestDb, crudTestCollection, o.F("surname", o.Eq("Explained")), ExplainExecutionStats))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, result.CollectionScan(), "surname has no index")
+	assert.ErrorIs(t, result.CheckIndexed(), ErrNotIndexed)
+	assert.Equal(t, int64(1), result.DocsReturned)
+
+	result, err = DialConnection(ctx, DoExplain(crudTestDb, crudTestCollection, o.F("surname", o.Eq("Explained")), ExplainQueryPlanner, WithSort("name"), WithLimit(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, result.InMemorySort(), "name has no index")
+}
@@ -0,0 +1,187 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrEmptyFilter is returned when a delete, a DeleteModel or a DoRestore has a nil or empty
+// filter, which would match the whole collection, and WithAllowFullCollection was not passed.
+var ErrEmptyFilter = errors.New("empty delete filter")
+
+// DeleteFunc returns the number of documents deleted, or the ones which would be deleted on a
+// dry run.
+type DeleteFunc func(context.Context, *mongo.Client) (deleted *int64, err error)
+
+// DeleteOption configures a delete.
+type DeleteOption func(*deleteSettings)
+
+type deleteSettings struct {
+	allowFullCollection bool
+	dryRun              bool
+	hint                any
+	collation           *options.Collation
+}
+
+// WithAllowFullCollection accepts a nil or empty filter, deleting every document.
+func WithAllowFullCollection() DeleteOption {
+	return func(s *deleteSettings) { s.allowFullCollection = true }
+}
+
+// WithDryRun deletes nothing and returns the number of documents which would be deleted.
+func WithDryRun() DeleteOption {
+	return func(s *deleteSettings) { s.dryRun = true }
+}
+
+// WithDeleteHint sets the index to use, by name or by its key document.
+func WithDeleteHint(hint any) DeleteOption {
+	return func(s *deleteSettings) { s.hint = hint }
+}
+
+// WithDeleteCollation sets the collation used to compare strings.
+func WithDeleteCollation(collation *options.Collation) DeleteOption {
+	return func(s *deleteSettings) { s.collation = collation }
+}
+
+func newDeleteSettings(opts []DeleteOption) deleteSettings {
+	var s deleteSettings
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+func (s deleteSettings) deleteOptions() *options.DeleteOptions {
+	do := options.Delete()
+	if s.hint != nil {
+		do.SetHint(s.hint)
+	}
+	if s.collation != nil {
+		do.SetCollation(s.collation)
+	}
+	return do
+}
+
+func (s deleteSettings) countOptions(limit int64) *options.CountOptions {
+	co := options.Count()
+	if limit > 0 {
+		co.SetLimit(limit)
+	}
+	if s.hint != nil {
+		co.SetHint(s.hint)
+	}
+	if s.collation != nil {
+		co.SetCollation(s.collation)
+	}
+	return co
+}
+
+// DoDeleteOne deletes the first document matching filter.
+func DoDeleteOne(db, col string, filter any, opts ...DeleteOption) DeleteFunc {
+	return Instrument(Operation{Name: "deleteOne", Database: db, Collection: col}, func(ctx context.Context, c *mongo.Client) (*int64, error) {
+		return deleteByFilter(ctx, c.Database(db).Collection(col), filter, false, newDeleteSettings(opts))
+	})
+}
+
+// DoDeleteMany deletes every document matching filter.
+func DoDeleteMany(db, col string, filter any, opts ...DeleteOption) DeleteFunc {
+	return Instrument(Operation{Name: "deleteMany", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*int64, error) {
+		return deleteByFilter(ctx, c.Database(db).Collection(col), filter, true, newDeleteSettings(opts))
+	})
+}
+
+func deleteByFilter(ctx context.Context, coll *mongo.Collection, filter any, many bool, s deleteSettings) (*int64, error) {
+	filter, err := guardEmptyFilter(filter, s)
+	if err != nil {
+		return nil, err
+	}
+
+	softDeleted := softDeleteEnabled(coll.Database().Name(), coll.Name())
+
+	if s.dryRun {
+		var limit int64
+		if !many {
+			limit = 1
+		}
+		if softDeleted {
+			filter = notDeleted(filter)
+		}
+
+		count, err := coll.CountDocuments(ctx, filter, s.countOptions(limit))
+		if err != nil {
+			return nil, translateError(err)
+		}
+		return &count, nil
+	}
+
+	if softDeleted {
+		return softDelete(ctx, coll, filter, many)
+	}
+
+	var dr *mongo.DeleteResult
+	if many {
+		dr, err = coll.DeleteMany(ctx, filter, s.deleteOptions())
+	} else {
+		dr, err = coll.DeleteOne(ctx, filter, s.deleteOptions())
+	}
+	if dr == nil {
+		return nil, translateError(err)
+	}
+
+	return &dr.DeletedCount, translateError(err)
+}
+
+// guardEmptyFilter returns ErrEmptyFilter when filter matches the whole collection, unless
+// WithAllowFullCollection was passed. Empty filters are returned as an empty bson.D.
+func guardEmptyFilter(filter any, s deleteSettings) (any, error) {
+	empty, err := isEmptyFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if !empty {
+		return filter, nil
+	}
+	if !s.allowFullCollection {
+		return nil, ErrEmptyFilter
+	}
+
+	return bson.D{}, nil
+}
+
+// isEmptyFilter reports whether filter is nil, including nil maps and slices, or a document
+// without fields.
+func isEmptyFilter(filter any) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+
+	switch v := reflect.ValueOf(filter); v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Pointer:
+		if v.IsNil() {
+			return true, nil
+		}
+	}
+
+	raw, ok := filter.(bson.Raw)
+	if !ok {
+		data, err := bson.Marshal(filter)
+		if err != nil {
+			return false, fmt.Errorf("invalid filter: %w", err)
+		}
+		raw = data
+	}
+
+	elems, err := raw.Elements()
+	if err != nil {
+		return false, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	return len(elems) == 0, nil
+}
@@ -0,0 +1,46 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	o "github.com/MrTimeout/go-mongo/operator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestIsEmptyFilter(t *testing.T) {
+	var testCases = []struct {
+		description string
+		filter      any
+		want        bool
+		wantErr     bool
+	}{
+		{description: "nil", filter: nil, want: true},
+		{description: "empty bson.D", filter: bson.D{}, want: true},
+		{description: "empty bson.M", filter: bson.M{}, want: true},
+		{description: "nil bson.D", filter: bson.D(nil), want: true},
+		{description: "empty struct", filter: struct{}{}, want: true},
+		{description: "operator filter", filter: o.F("name", o.Eq("x"))},
+		{description: "bson.M with fields", filter: bson.M{"name": "x"}},
+		{description: "not a document", filter: 3, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := isEmptyFilter(tc.filter)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDeleteRefusesEmptyFilter(t *testing.T) {
+	_, err := deleteByFilter(context.TODO(), nil, bson.D{}, true, newDeleteSettings(nil))
+	assert.ErrorIs(t, err, ErrEmptyFilter)
+}
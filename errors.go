@@ -0,0 +1,192 @@
+package mongodb
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Sentinel errors returned by the CRUD helpers. Use errors.Is to check them and errors.As
+// with the *Error types below to get the details. The driver error is always available
+// through errors.Unwrap.
+var (
+	ErrNotFound        = errors.New("document not found")
+	ErrDuplicateKey    = errors.New("duplicate key")
+	ErrInvalidObjectID = errors.New("invalid object id")
+	ErrValidation      = errors.New("document failed validation")
+	ErrTimeout         = errors.New("operation timed out")
+	ErrConflict        = errors.New("version conflict")
+)
+
+// DuplicateKeyError is returned when a write violates a unique index.
+type DuplicateKeyError struct {
+	// Index is the position of the failed document in the input, 0 for single document writes.
+	Index      int
+	KeyPattern bson.M
+	KeyValue   bson.M
+	Err        error
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("%v: index %d, key %v: %v", ErrDuplicateKey, e.Index, e.KeyValue, e.Err)
+}
+
+func (e *DuplicateKeyError) Is(target error) bool { return target == ErrDuplicateKey }
+
+func (e *DuplicateKeyError) Unwrap() error { return e.Err }
+
+// InvalidObjectIDError is returned when a string is not a valid hex ObjectID.
+type InvalidObjectIDError struct {
+	// Index is the position of the offending string in the input.
+	Index int
+	Input string
+	Err   error
+}
+
+func (e *InvalidObjectIDError) Error() string {
+	return fmt.Sprintf("%v: index %d, %q: %v", ErrInvalidObjectID, e.Index, e.Input, e.Err)
+}
+
+func (e *InvalidObjectIDError) Is(target error) bool { return target == ErrInvalidObjectID }
+
+func (e *InvalidObjectIDError) Unwrap() error { return e.Err }
+
+// ValidationError is returned when a document does not pass the collection validator.
+type ValidationError struct {
+	// Index is the position of the failed document in the input, 0 for single document writes.
+	Index int
+	// Details is the errInfo sent by the server, explaining which rule failed.
+	Details bson.Raw
+	Err     error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: index %d: %v", ErrValidation, e.Index, e.Err)
+}
+
+func (e *ValidationError) Is(target error) bool { return target == ErrValidation }
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// ConflictError is returned when a document was changed by someone else since it was read:
+// its version field no longer has the expected value.
+type ConflictError struct {
+	Expected int64
+	// Current is the version stored in the database.
+	Current int64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: expected version %d, current %d", ErrConflict, e.Expected, e.Current)
+}
+
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+// kindError tags a driver error with one of the sentinel errors.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string { return fmt.Sprintf("%v: %v", e.kind, e.err) }
+
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+func (e *kindError) Unwrap() error { return e.err }
+
+// translateError turns a driver error into one of the typed errors of the package. Errors
+// which do not match any of them are returned untouched.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var (
+		writeErr   mongo.WriteException
+		bulkErr    mongo.BulkWriteException
+		commandErr mongo.CommandError
+	)
+
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return &kindError{kind: ErrNotFound, err: err}
+	case errors.As(err, &writeErr):
+		if translated := translateWriteErrors(err, writeErr.WriteErrors); translated != nil {
+			return translated
+		}
+	case errors.As(err, &bulkErr):
+		writeErrors := make(mongo.WriteErrors, len(bulkErr.WriteErrors))
+		for i := range bulkErr.WriteErrors {
+			writeErrors[i] = bulkErr.WriteErrors[i].WriteError
+		}
+		if translated := translateWriteErrors(err, writeErrors); translated != nil {
+			return translated
+		}
+	case errors.As(err, &commandErr):
+		// findAndModify reports write failures as command errors.
+		if hasAnyCode(commandErr, duplicateKeyCodes) {
+			return newDuplicateKeyError(0, commandErr.Raw, err)
+		}
+		if commandErr.HasErrorCode(codeDocumentValidationFailure) {
+			return &ValidationError{Details: lookupRaw(commandErr.Raw, "errInfo"), Err: err}
+		}
+	}
+
+	if mongo.IsTimeout(err) {
+		return &kindError{kind: ErrTimeout, err: err}
+	}
+
+	return err
+}
+
+// translateWriteErrors returns the typed error for the first write error we know about.
+func translateWriteErrors(err error, writeErrors mongo.WriteErrors) error {
+	for _, we := range writeErrors {
+		if typed := typedWriteError(we.Index, we, err); typed != nil {
+			return typed
+		}
+	}
+
+	return nil
+}
+
+// typedWriteError returns the typed error matching we, reported at index, or nil if there is none.
+func typedWriteError(index int, we mongo.WriteError, err error) error {
+	switch we.Code {
+	case codeDuplicateKey, codeDuplicateKeyLegacy, codeDuplicateKeyUpdate:
+		return newDuplicateKeyError(index, we.Raw, err)
+	case codeDocumentValidationFailure:
+		return &ValidationError{Index: index, Details: we.Details, Err: err}
+	}
+
+	return nil
+}
+
+func newDuplicateKeyError(index int, raw bson.Raw, err error) *DuplicateKeyError {
+	dke := &DuplicateKeyError{Index: index, Err: err}
+
+	if pattern := lookupRaw(raw, "keyPattern"); pattern != nil {
+		_ = bson.Unmarshal(pattern, &dke.KeyPattern)
+	}
+	if value := lookupRaw(raw, "keyValue"); value != nil {
+		_ = bson.Unmarshal(value, &dke.KeyValue)
+	}
+
+	return dke
+}
+
+// lookupRaw returns the embedded document under key, or nil if there is none.
+func lookupRaw(raw bson.Raw, key string) bson.Raw {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	doc, ok := raw.Lookup(key).DocumentOK()
+	if !ok {
+		return nil
+	}
+
+	return doc
+}
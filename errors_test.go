@@ -0,0 +1,94 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestTranslateError(t *testing.T) {
+	dupRaw := bsonMustMarshal(t, bson.D{
+		{Key: "code", Value: codeDuplicateKey},
+		{Key: "keyPattern", Value: bson.D{{Key: "email", Value: 1}}},
+		{Key: "keyValue", Value: bson.D{{Key: "email", Value: "john@example.com"}}},
+	})
+
+	t.Run("no documents is not found", func(t *testing.T) {
+		err := translateError(mongo.ErrNoDocuments)
+
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+	})
+
+	t.Run("duplicate key carries the index, the key pattern and the value", func(t *testing.T) {
+		driverErr := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Index: 2, Code: codeDuplicateKey, Raw: dupRaw}}}
+		err := translateError(driverErr)
+
+		var dke *DuplicateKeyError
+		assert.True(t, errors.As(err, &dke))
+		assert.ErrorIs(t, err, ErrDuplicateKey)
+		assert.Equal(t, 2, dke.Index)
+		assert.Equal(t, bson.M{"email": int32(1)}, dke.KeyPattern)
+		assert.Equal(t, bson.M{"email": "john@example.com"}, dke.KeyValue)
+
+		var we mongo.WriteException
+		assert.True(t, errors.As(err, &we), "the driver error is still available")
+	})
+
+	t.Run("duplicate key from a find and modify command", func(t *testing.T) {
+		err := translateError(mongo.CommandError{Code: codeDuplicateKey, Raw: dupRaw})
+
+		var dke *DuplicateKeyError
+		assert.True(t, errors.As(err, &dke))
+		assert.Equal(t, bson.M{"email": "john@example.com"}, dke.KeyValue)
+	})
+
+	t.Run("validation failure", func(t *testing.T) {
+		details := bson.Raw(bsonMustMarshal(t, bson.D{{Key: "failingDocumentId", Value: 1}}))
+		err := translateError(mongo.WriteException{WriteErrors: mongo.WriteErrors{{Index: 1, Code: codeDocumentValidationFailure, Details: details}}})
+
+		var ve *ValidationError
+		assert.True(t, errors.As(err, &ve))
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.Equal(t, 1, ve.Index)
+		assert.Equal(t, details, ve.Details)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		err := translateError(context.DeadlineExceeded)
+
+		assert.ErrorIs(t, err, ErrTimeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("unknown errors are untouched", func(t *testing.T) {
+		boom := errors.New("boom")
+		assert.Equal(t, boom, translateError(boom))
+		assert.Nil(t, translateError(nil))
+	})
+}
+
+func TestDeleteByStringObjectIDInvalidHex(t *testing.T) {
+	_, err := DoDeleteByStringObjectID(crudTestDb, crudTestCollection, primitive.NewObjectID().Hex(), "not-an-id")(context.TODO(), nil)
+
+	var ie *InvalidObjectIDError
+	assert.True(t, errors.As(err, &ie))
+	assert.ErrorIs(t, err, ErrInvalidObjectID)
+	assert.ErrorIs(t, err, primitive.ErrInvalidHex)
+	assert.Equal(t, 1, ie.Index)
+	assert.Equal(t, "not-an-id", ie.Input)
+}
+
+func bsonMustMarshal(t *testing.T, v any) []byte {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
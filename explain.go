@@ -0,0 +1,295 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExplainVerbosity is how much the server runs of the explained query.
+type ExplainVerbosity string
+
+// Verbosities of DoExplain. ExplainQueryPlanner does not run the query, so the execution
+// stats of the result are zero.
+const (
+	ExplainQueryPlanner      ExplainVerbosity = "queryPlanner"
+	ExplainExecutionStats    ExplainVerbosity = "executionStats"
+	ExplainAllPlansExecution ExplainVerbosity = "allPlansExecution"
+)
+
+// ErrNotIndexed is returned by ExplainResult.CheckIndexed when a query scans the whole
+// collection or sorts in memory.
+var ErrNotIndexed = errors.New("query is not fully indexed")
+
+// PlanStage is a stage of a query plan, with the stages feeding it.
+type PlanStage struct {
+	Stage       string      `bson:"stage"`
+	IndexName   string      `bson:"indexName,omitempty"`
+	KeyPattern  bson.Raw    `bson:"keyPattern,omitempty"`
+	InputStage  *PlanStage  `bson:"inputStage,omitempty"`
+	InputStages []PlanStage `bson:"inputStages,omitempty"`
+}
+
+// walk calls fn with s and every stage below it, parents first.
+func (s *PlanStage) walk(fn func(*PlanStage)) {
+	if s == nil {
+		return
+	}
+
+	fn(s)
+	s.InputStage.walk(fn)
+	for i := range s.InputStages {
+		s.InputStages[i].walk(fn)
+	}
+}
+
+// ExplainResult is the summary of the explain output of a query.
+type ExplainResult struct {
+	// WinningPlan is the plan chosen by the server.
+	WinningPlan PlanStage
+	// Stages are the stages of WinningPlan, parents first, like ["FETCH", "IXSCAN"].
+	Stages []string
+	// PipelineStages are the aggregation stages run after the query, like ["$group", "$sort"].
+	PipelineStages []string
+	// Indexes are the names of the indexes used.
+	Indexes []string
+
+	// Execution stats, only set with ExplainExecutionStats or ExplainAllPlansExecution.
+	DocsExamined  int64
+	KeysExamined  int64
+	DocsReturned  int64
+	ExecutionTime time.Duration
+
+	// Raw is the whole explain output.
+	Raw bson.Raw
+}
+
+// HasStage reports whether the winning plan, or the pipeline, has stage.
+func (r *ExplainResult) HasStage(stage string) bool {
+	for _, s := range r.Stages {
+		if s == stage {
+			return true
+		}
+	}
+	for _, s := range r.PipelineStages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectionScan reports whether the query reads the whole collection instead of an index.
+func (r *ExplainResult) CollectionScan() bool {
+	return r.HasStage("COLLSCAN")
+}
+
+// InMemorySort reports whether the documents are sorted in memory instead of read in the
+// order of an index.
+func (r *ExplainResult) InMemorySort() bool {
+	return r.HasStage("SORT") || r.HasStage("$sort")
+}
+
+// CheckIndexed returns an error matching ErrNotIndexed when the query scans the collection or
+// sorts in memory, nil otherwise. Handy to assert in tests that critical queries stay indexed.
+func (r *ExplainResult) CheckIndexed() error {
+	var problems []string
+	if r.CollectionScan() {
+		problems = append(problems, "collection scan")
+	}
+	if r.InMemorySort() {
+		problems = append(problems, "in-memory sort")
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s, plan %s", ErrNotIndexed, strings.Join(problems, " and "), strings.Join(r.Stages, " <- "))
+}
+
+// DoExplain explains query, a filter run as a find or a pipeline run as an aggregate. opts
+// are applied to the find, so a sort the indexes do not cover shows as an in-memory sort;
+// they are ignored for pipelines.
+func DoExplain(db, col string, query any, verbosity ExplainVerbosity, opts ...FindOption) DocumentFunc[ExplainResult] {
+	return Instrument(Operation{Name: "explain", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*ExplainResult, error) {
+		var cmd bson.D
+		if isPipeline(query) {
+			cmd = bson.D{{Key: "aggregate", Value: col}, {Key: "pipeline", Value: query}, {Key: "cursor", Value: bson.D{}}}
+		} else {
+			cmd = findCommand(col, visibleFilter(ctx, db, col, orEmptyFilter(query)), findOptions(opts))
+		}
+
+		return explain(ctx, c.Database(db), cmd, verbosity)
+	})
+}
+
+// findCommand builds the find command run by a find with fo.
+func findCommand(col string, filter any, fo *options.FindOptions) bson.D {
+	cmd := bson.D{{Key: "find", Value: col}, {Key: "filter", Value: filter}}
+	if fo.Sort != nil {
+		cmd = append(cmd, bson.E{Key: "sort", Value: fo.Sort})
+	}
+	if fo.Projection != nil {
+		cmd = append(cmd, bson.E{Key: "projection", Value: fo.Projection})
+	}
+	if fo.Skip != nil {
+		cmd = append(cmd, bson.E{Key: "skip", Value: *fo.Skip})
+	}
+	if fo.Limit != nil {
+		cmd = append(cmd, bson.E{Key: "limit", Value: *fo.Limit})
+	}
+	if fo.Hint != nil {
+		cmd = append(cmd, bson.E{Key: "hint", Value: fo.Hint})
+	}
+
+	return cmd
+}
+
+// DoExplainCount explains the count of the documents matching filter.
+func DoExplainCount(db, col string, filter any, verbosity ExplainVerbosity) DocumentFunc[ExplainResult] {
+	return Instrument(Operation{Name: "explain", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*ExplainResult, error) {
+		cmd := bson.D{{Key: "count", Value: col}, {Key: "query", Value: visibleFilter(ctx, db, col, orEmptyFilter(filter))}}
+
+		return explain(ctx, c.Database(db), cmd, verbosity)
+	})
+}
+
+func explain(ctx context.Context, db *mongo.Database, cmd bson.D, verbosity ExplainVerbosity) (*ExplainResult, error) {
+	if verbosity == "" {
+		verbosity = ExplainQueryPlanner
+	}
+
+	raw, err := db.RunCommand(ctx, bson.D{{Key: "explain", Value: cmd}, {Key: "verbosity", Value: string(verbosity)}}).DecodeBytes()
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return parseExplain(raw)
+}
+
+type explainOutput struct {
+	QueryPlanner   *explainPlanner `bson:"queryPlanner"`
+	ExecutionStats *explainStats   `bson:"executionStats"`
+	Stages         []bson.Raw      `bson:"stages"`
+}
+
+type explainPlanner struct {
+	WinningPlan bson.Raw `bson:"winningPlan"`
+}
+
+type explainStats struct {
+	NReturned           int64 `bson:"nReturned"`
+	ExecutionTimeMillis int64 `bson:"executionTimeMillis"`
+	TotalKeysExamined   int64 `bson:"totalKeysExamined"`
+	TotalDocsExamined   int64 `bson:"totalDocsExamined"`
+}
+
+// parseExplain summarizes the output of explain, for finds, counts and aggregates, on
+// replica sets and sharded clusters.
+func parseExplain(raw bson.Raw) (*ExplainResult, error) {
+	var out explainOutput
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+
+	// When the pipeline is not run by the query layer alone, the query is explained in the
+	// $cursor of its first stage.
+	if out.QueryPlanner == nil && len(out.Stages) > 0 {
+		cursor, ok := out.Stages[0].Lookup("$cursor").DocumentOK()
+		if !ok {
+			return nil, errors.New("explain: no query planner in the output")
+		}
+
+		var cursorOut explainOutput
+		if err := bson.Unmarshal(cursor, &cursorOut); err != nil {
+			return nil, err
+		}
+		out.QueryPlanner, out.ExecutionStats = cursorOut.QueryPlanner, cursorOut.ExecutionStats
+	}
+
+	if out.QueryPlanner == nil {
+		return nil, errors.New("explain: no query planner in the output")
+	}
+
+	result := &ExplainResult{Raw: raw}
+
+	plan, err := parsePlan(out.QueryPlanner.WinningPlan)
+	if err != nil {
+		return nil, err
+	}
+	result.WinningPlan = *plan
+
+	result.WinningPlan.walk(func(s *PlanStage) {
+		result.Stages = append(result.Stages, s.Stage)
+		if s.IndexName != "" {
+			result.Indexes = append(result.Indexes, s.IndexName)
+		}
+	})
+
+	for _, stage := range out.Stages {
+		elems, err := stage.Elements()
+		if err != nil || len(elems) == 0 || elems[0].Key() == "$cursor" {
+			continue
+		}
+		result.PipelineStages = append(result.PipelineStages, elems[0].Key())
+	}
+
+	if stats := out.ExecutionStats; stats != nil {
+		result.DocsExamined = stats.TotalDocsExamined
+		result.KeysExamined = stats.TotalKeysExamined
+		result.DocsReturned = stats.NReturned
+		result.ExecutionTime = time.Duration(stats.ExecutionTimeMillis) * time.Millisecond
+	}
+
+	return result, nil
+}
+
+// parsePlan decodes a winning plan. The slot based engine wraps it in queryPlan, and sharded
+// clusters list the plan of every shard.
+func parsePlan(raw bson.Raw) (*PlanStage, error) {
+	if queryPlan, ok := raw.Lookup("queryPlan").DocumentOK(); ok {
+		raw = queryPlan
+	}
+
+	var plan PlanStage
+	if err := bson.Unmarshal(raw, &plan); err != nil {
+		return nil, err
+	}
+
+	shards, ok := raw.Lookup("shards").ArrayOK()
+	if !ok {
+		return &plan, nil
+	}
+
+	values, err := shards.Values()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range values {
+		shard, ok := value.DocumentOK()
+		if !ok {
+			continue
+		}
+
+		shardPlan, ok := shard.Lookup("winningPlan").DocumentOK()
+		if !ok {
+			continue
+		}
+
+		stage, err := parsePlan(shardPlan)
+		if err != nil {
+			return nil, err
+		}
+		plan.InputStages = append(plan.InputStages, *stage)
+	}
+
+	return &plan, nil
+}
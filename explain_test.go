@@ -0,0 +1,172 @@
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseExplain(t *testing.T) {
+	var (
+		ixscan = bson.D{
+			{Key: "stage", Value: "IXSCAN"},
+			{Key: "keyPattern", Value: bson.D{{Key: "surname", Value: 1}}},
+			{Key: "indexName", Value: "surname_1"},
+		}
+		fetch    = bson.D{{Key: "stage", Value: "FETCH"}, {Key: "inputStage", Value: ixscan}}
+		collscan = bson.D{{Key: "stage", Value: "COLLSCAN"}}
+		sort     = bson.D{{Key: "stage", Value: "SORT"}, {Key: "inputStage", Value: collscan}}
+		ixsort   = bson.D{
+			{Key: "stage", Value: "SORT"},
+			{Key: "sortPattern", Value: bson.D{{Key: "name", Value: 1}}},
+			{Key: "inputStage", Value: fetch},
+		}
+		stats = bson.D{
+			{Key: "nReturned", Value: int32(3)},
+			{Key: "executionTimeMillis", Value: int32(12)},
+			{Key: "totalKeysExamined", Value: int32(3)},
+			{Key: "totalDocsExamined", Value: int32(3)},
+		}
+	)
+
+	var testCases = []struct {
+		description    string
+		output         bson.D
+		wantStages     []string
+		wantPipeline   []string
+		wantIndexes    []string
+		wantIndexed    bool
+		wantReturned   int64
+		wantExecution  time.Duration
+		wantCollScan   bool
+		wantMemorySort bool
+	}{
+		{
+			description: "indexed find with execution stats",
+			output: bson.D{
+				{Key: "queryPlanner", Value: bson.D{{Key: "winningPlan", Value: fetch}}},
+				{Key: "executionStats", Value: stats},
+			},
+			wantStages:    []string{"FETCH", "IXSCAN"},
+			wantIndexes:   []string{"surname_1"},
+			wantIndexed:   true,
+			wantReturned:  3,
+			wantExecution: 12 * time.Millisecond,
+		},
+		{
+			description: "find scanning the collection and sorting in memory",
+			output: bson.D{
+				{Key: "queryPlanner", Value: bson.D{{Key: "winningPlan", Value: sort}}},
+			},
+			wantStages:     []string{"SORT", "COLLSCAN"},
+			wantCollScan:   true,
+			wantMemorySort: true,
+		},
+		{
+			description: "indexed find sorting in memory",
+			output: bson.D{
+				{Key: "queryPlanner", Value: bson.D{{Key: "winningPlan", Value: ixsort}}},
+			},
+			wantStages:     []string{"SORT", "FETCH", "IXSCAN"},
+			wantIndexes:    []string{"surname_1"},
+			wantMemorySort: true,
+		},
+		{
+			description: "plans of the slot based engine",
+			output: bson.D{
+				{Key: "queryPlanner", Value: bson.D{{Key: "winningPlan", Value: bson.D{
+					{Key: "queryPlan", Value: fetch},
+					{Key: "slotBasedPlan", Value: bson.D{{Key: "stages", Value: "..."}}},
+				}}}},
+			},
+			wantStages:  []string{"FETCH", "IXSCAN"},
+			wantIndexes: []string{"surname_1"},
+			wantIndexed: true,
+		},
+		{
+			description: "aggregate explained in its $cursor stage",
+			output: bson.D{
+				{Key: "stages", Value: bson.A{
+					bson.D{{Key: "$cursor", Value: bson.D{
+						{Key: "queryPlanner", Value: bson.D{{Key: "winningPlan", Value: fetch}}},
+						{Key: "executionStats", Value: stats},
+					}}},
+					bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$surname"}}}},
+					bson.D{{Key: "$sort", Value: bson.D{{Key: "sortKey", Value: bson.D{{Key: "_id", Value: 1}}}}}},
+				}},
+			},
+			wantStages:     []string{"FETCH", "IXSCAN"},
+			wantPipeline:   []string{"$group", "$sort"},
+			wantIndexes:    []string{"surname_1"},
+			wantReturned:   3,
+			wantExecution:  12 * time.Millisecond,
+			wantMemorySort: true,
+		},
+		{
+			description: "sharded cluster",
+			output: bson.D{
+				{Key: "queryPlanner", Value: bson.D{{Key: "winningPlan", Value: bson.D{
+					{Key: "stage", Value: "SHARD_MERGE"},
+					{Key: "shards", Value: bson.A{
+						bson.D{{Key: "shardName", Value: "a"}, {Key: "winningPlan", Value: fetch}},
+						bson.D{{Key: "shardName", Value: "b"}, {Key: "winningPlan", Value: collscan}},
+					}},
+				}}}},
+			},
+			wantStages:   []string{"SHARD_MERGE", "FETCH", "IXSCAN", "COLLSCAN"},
+			wantIndexes:  []string{"surname_1"},
+			wantCollScan: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := parseExplain(bsonMustMarshal(t, tc.output))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tc.wantStages, got.Stages)
+			assert.Equal(t, tc.wantPipeline, got.PipelineStages)
+			assert.Equal(t, tc.wantIndexes, got.Indexes)
+			assert.Equal(t, tc.wantReturned, got.DocsReturned)
+			assert.Equal(t, tc.wantExecution, got.ExecutionTime)
+			assert.Equal(t, tc.wantCollScan, got.CollectionScan())
+			assert.Equal(t, tc.wantMemorySort, got.InMemorySort())
+
+			if tc.wantIndexed {
+				assert.NoError(t, got.CheckIndexed())
+			} else {
+				assert.ErrorIs(t, got.CheckIndexed(), ErrNotIndexed)
+			}
+		})
+	}
+
+	_, err := parseExplain(bsonMustMarshal(t, bson.D{{Key: "ok", Value: 1}}))
+	assert.Error(t, err)
+}
+
+func TestFindCommand(t *testing.T) {
+	filter := bson.D{{Key: "surname", Value: "Smith"}}
+
+	assert.Equal(t, bson.D{
+		{Key: "find", Value: "people"},
+		{Key: "filter", Value: filter},
+	}, findCommand("people", filter, findOptions(nil)))
+
+	assert.Equal(t, bson.D{
+		{Key: "find", Value: "people"},
+		{Key: "filter", Value: filter},
+		{Key: "sort", Value: bson.D{{Key: "name", Value: 1}, {Key: "age", Value: -1}}},
+		{Key: "projection", Value: bson.D{{Key: "name", Value: 1}}},
+		{Key: "skip", Value: int64(20)},
+		{Key: "limit", Value: int64(10)},
+	}, findCommand("people", filter, findOptions([]FindOption{
+		WithLimit(10),
+		WithSkip(20),
+		WithSort("name", "-age"),
+		WithProjection(bson.D{{Key: "name", Value: 1}}),
+	})))
+}
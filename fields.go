@@ -0,0 +1,147 @@
+package mongodb
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// docField locates a field of a struct marked with an option of the mongo tag, like
+// `mongo:"version"`.
+type docField struct {
+	index []int
+	name  string
+}
+
+type taggedFieldKey struct {
+	t   reflect.Type
+	tag string
+}
+
+var taggedFields sync.Map // taggedFieldKey -> *docField, nil when there is none
+
+// taggedField returns the first field of t marked with tag whose type is accepted, looking
+// into inline structs, or nil if there is none.
+func taggedField(t reflect.Type, tag string, accept func(reflect.Type) bool) *docField {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	key := taggedFieldKey{t: t, tag: tag}
+	if cached, ok := taggedFields.Load(key); ok {
+		return cached.(*docField)
+	}
+
+	f := findTaggedField(t, nil, tag, accept)
+	taggedFields.Store(key, f)
+
+	return f
+}
+
+func findTaggedField(t reflect.Type, index []int, tag string, accept func(reflect.Type) bool) *docField {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, inline, skip := parseBSONTag(field)
+		if skip {
+			continue
+		}
+
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		if inline && field.Type.Kind() == reflect.Struct {
+			if f := findTaggedField(field.Type, fieldIndex, tag, accept); f != nil {
+				return f
+			}
+			continue
+		}
+
+		if hasMongoTag(field, tag) && accept(field.Type) {
+			return &docField{index: fieldIndex, name: name}
+		}
+	}
+
+	return nil
+}
+
+func hasMongoTag(field reflect.StructField, option string) bool {
+	for _, opt := range strings.Split(field.Tag.Get("mongo"), ",") {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// appendStage returns pipeline, an update pipeline, with stage at the end.
+func appendStage(pipeline any, stage bson.D) bson.A {
+	rv := reflect.ValueOf(pipeline)
+
+	stages := make(bson.A, 0, rv.Len()+1)
+	for i := 0; i < rv.Len(); i++ {
+		stages = append(stages, rv.Index(i).Interface())
+	}
+
+	return append(stages, stage)
+}
+
+// updateDocument returns update, made of update operators, as a bson.D which can be extended.
+func updateDocument(update any) (bson.D, error) {
+	data, err := bson.Marshal(update)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUpdate, err)
+	}
+
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUpdate, err)
+	}
+
+	return doc, nil
+}
+
+// withOperator adds e to the fields of the operator op of doc, adding the operator if missing.
+// doc is left as it is when any of its operators already changes the field of e, as the
+// server rejects two changes of the same path.
+func withOperator(doc bson.D, op string, e bson.E) (bson.D, error) {
+	for _, operator := range doc {
+		if fields, ok := operator.Value.(bson.D); ok && hasKey(fields, e.Key) {
+			return doc, nil
+		}
+	}
+
+	for i := range doc {
+		if doc[i].Key != op {
+			continue
+		}
+
+		fields, ok := doc[i].Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not a document", ErrInvalidUpdate, op)
+		}
+		doc[i].Value = append(fields, e)
+
+		return doc, nil
+	}
+
+	return append(doc, bson.E{Key: op, Value: bson.D{e}}), nil
+}
+
+func hasKey(doc bson.D, key string) bool {
+	for _, e := range doc {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FindAllFunc returns every document matched, decoded as T.
+type FindAllFunc[T any] func(context.Context, *mongo.Client) (*[]T, error)
+
+// DocumentFunc returns a single document decoded as T.
+type DocumentFunc[T any] func(context.Context, *mongo.Client) (*T, error)
+
+// FindOption configures a find without building options.FindOptions by hand.
+type FindOption func(*options.FindOptions)
+
+// WithSort sorts by the fields passed, in order. A field prefixed with "-" is sorted descending.
+func WithSort(fields ...string) FindOption {
+	return func(o *options.FindOptions) { o.SetSort(sortDocument(fields)) }
+}
+
+// WithLimit returns at most n documents.
+func WithLimit(n int64) FindOption {
+	return func(o *options.FindOptions) { o.SetLimit(n) }
+}
+
+// WithSkip skips the first n documents.
+func WithSkip(n int64) FindOption {
+	return func(o *options.FindOptions) { o.SetSkip(n) }
+}
+
+// WithProjection sets the fields returned, like bson.D{{Key: "name", Value: 1}}.
+func WithProjection(projection any) FindOption {
+	return func(o *options.FindOptions) { o.SetProjection(projection) }
+}
+
+func sortDocument(fields []string) bson.D {
+	sort := make(bson.D, len(fields))
+
+	for i, field := range fields {
+		if strings.HasPrefix(field, "-") {
+			sort[i] = bson.E{Key: field[1:], Value: -1}
+		} else {
+			sort[i] = bson.E{Key: strings.TrimPrefix(field, "+"), Value: 1}
+		}
+	}
+
+	return sort
+}
+
+func findOptions(opts []FindOption) *options.FindOptions {
+	fo := options.Find()
+	for _, opt := range opts {
+		opt(fo)
+	}
+	return fo
+}
+
+func findOneOptions(opts []FindOption) *options.FindOneOptions {
+	fo := findOptions(opts)
+
+	foo := options.FindOne()
+	if fo.Sort != nil {
+		foo.SetSort(fo.Sort)
+	}
+	if fo.Skip != nil {
+		foo.SetSkip(*fo.Skip)
+	}
+	if fo.Projection != nil {
+		foo.SetProjection(fo.Projection)
+	}
+
+	return foo
+}
+
+// DoFindAll returns every document matching filter.
+func DoFindAll[T any](db, col string, filter any, opts ...FindOption) FindAllFunc[T] {
+	return Instrument(Operation{Name: "find", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*[]T, error) {
+		result := []T{}
+		if err := find(ctx, c, db, col, filter, &result, findOptions(opts)); err != nil {
+			return nil, err
+		}
+
+		return &result, nil
+	})
+}
+
+// DoFindOne returns the first document matching filter, or ErrNotFound. WithLimit is ignored.
+func DoFindOne[T any](db, col string, filter any, opts ...FindOption) DocumentFunc[T] {
+	return Instrument(Operation{Name: "findOne", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*T, error) {
+		return decodeSingleResult(ctx, c.Database(db).Collection(col).FindOne(ctx, visibleFilter(ctx, db, col, filter), findOneOptions(opts)), afterFind[T])
+	})
+}
+
+// ErrStopIteration can be returned by the callback of DoFindEach to stop without failing.
+var ErrStopIteration = errors.New("stop iteration")
+
+// CountFunc returns a number of documents.
+type CountFunc func(context.Context, *mongo.Client) (*int64, error)
+
+// WithBatchSize sets how many documents the server returns on every round trip.
+func WithBatchSize(n int32) FindOption {
+	return func(o *options.FindOptions) { o.SetBatchSize(n) }
+}
+
+// DoFindEach decodes the documents matching filter one at a time and calls fn with each of
+// them, so the result set is never held in memory. It stops at the first error returned by
+// fn, or when ctx is done, and returns how many documents fn received. Once fn received a
+// document the query is no longer retried, it would receive it again.
+func DoFindEach[T any](db, col string, filter any, fn func(T) error, opts ...FindOption) CountFunc {
+	return Instrument(Operation{Name: "find", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*int64, error) {
+		cursor, err := c.Database(db).Collection(col).Find(ctx, visibleFilter(ctx, db, col, filter), findOptions(opts))
+		if err != nil {
+			return new(int64), translateError(err)
+		}
+		defer closeCursor(cursor)
+
+		return eachDocument(ctx, cursor, fn)
+	})
+}
+
+// eachDocument calls fn with the documents of cursor. Errors after the first document are
+// wrapped in a *deliveredError.
+func eachDocument[T any](ctx context.Context, cursor *mongo.Cursor, fn func(T) error) (*int64, error) {
+	var count int64
+
+	fail := func(err error) (*int64, error) {
+		if count > 0 {
+			err = &deliveredError{err: err}
+		}
+		return &count, err
+	}
+
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return fail(err)
+		}
+		if err := afterFind(ctx, &doc); err != nil {
+			return fail(err)
+		}
+
+		count++
+		if err := fn(doc); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return &count, nil
+			}
+			return fail(err)
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fail(translateError(err))
+	}
+
+	return &count, nil
+}
+
+// deliveredError is a failure of a query which already handed documents to its caller, so
+// it is never retried.
+type deliveredError struct {
+	err error
+}
+
+func (e *deliveredError) Error() string {
+	return e.err.Error()
+}
+
+func (e *deliveredError) Unwrap() error {
+	return e.err
+}
+
+// FindStream runs with run, usually DialConnection[int64], a query sending the documents
+// matching filter to the channel returned, one at a time. Sends block until the receiver is
+// ready; cancel ctx to stop early. The channel is closed once run returns, even when it
+// never executed the query or executed it several times, and wait then returns how many
+// documents the last execution sent and the error of run.
+func FindStream[T any](ctx context.Context, run func(context.Context, func(context.Context, *mongo.Client) (*int64, error)) (*int64, error), db, col string, filter any, opts ...FindOption) (docs <-chan T, wait func() (int64, error)) {
+	return stream(ctx, run, func(send func(T) error) CountFunc {
+		return DoFindEach(db, col, filter, send, opts...)
+	})
+}
+
+// stream runs the query returned by each, which calls send with every document, and sends
+// them to the channel returned.
+func stream[T any](ctx context.Context, run func(context.Context, func(context.Context, *mongo.Client) (*int64, error)) (*int64, error), each func(send func(T) error) CountFunc) (<-chan T, func() (int64, error)) {
+	var (
+		out   = make(chan T)
+		done  = make(chan struct{})
+		count int64
+		err   error
+	)
+
+	send := func(doc T) error {
+		select {
+		case out <- doc:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	go func() {
+		defer close(done)
+		defer close(out)
+
+		var n *int64
+		if n, err = run(ctx, each(send)); n != nil {
+			count = *n
+		}
+	}()
+
+	return out, func() (int64, error) {
+		<-done
+		return count, err
+	}
+}
+
+// closeCursor kills the server cursor even if the context of the query is already done.
+func closeCursor(cursor *mongo.Cursor) {
+	ctx, cl := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cl()
+
+	_ = cursor.Close(ctx)
+}
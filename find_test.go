@@ -0,0 +1,163 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestFindOptions(t *testing.T) {
+	projection := bson.D{{Key: "name", Value: 1}}
+	opts := []FindOption{WithSort("-age", "name"), WithLimit(10), WithSkip(20), WithProjection(projection), WithBatchSize(500)}
+
+	fo := findOptions(opts)
+
+	assert.Equal(t, bson.D{{Key: "age", Value: -1}, {Key: "name", Value: 1}}, fo.Sort)
+	assert.Equal(t, int64(10), *fo.Limit)
+	assert.Equal(t, int64(20), *fo.Skip)
+	assert.Equal(t, projection, fo.Projection)
+	assert.Equal(t, int32(500), *fo.BatchSize)
+
+	foo := findOneOptions(opts)
+
+	assert.Equal(t, fo.Sort, foo.Sort)
+	assert.Equal(t, int64(20), *foo.Skip)
+	assert.Equal(t, projection, foo.Projection)
+}
+
+func TestFindEachRetry(t *testing.T) {
+	t.Cleanup(ClearMiddlewares)
+	Use(Retry(RetryPolicy{Backoff: Backoff{Initial: time.Millisecond, MaxAttempts: 3}}))
+
+	networkErr := mongo.CommandError{Labels: []string{"NetworkError"}}
+
+	var testCases = []struct {
+		description   string
+		failOpen      int
+		failAfter     int
+		wantAttempts  int
+		wantDelivered []int
+	}{
+		{
+			description:   "failures before the first document are retried",
+			failOpen:      2,
+			wantAttempts:  3,
+			wantDelivered: []int{1, 2, 3},
+		},
+		{
+			description:   "failures after a document was delivered are not retried",
+			failAfter:     2,
+			wantAttempts:  1,
+			wantDelivered: []int{1, 2},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.description, func(t *testing.T) {
+			var (
+				attempts  int
+				delivered []int
+			)
+
+			query := Instrument(Operation{Name: "find", Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*int64, error) {
+				attempts++
+				if attempts <= tCase.failOpen {
+					return new(int64), networkErr
+				}
+
+				cursor, err := mongo.NewCursorFromDocuments([]any{bson.D{{Key: "n", Value: 1}}, bson.D{{Key: "n", Value: 2}}, bson.D{{Key: "n", Value: 3}}}, nil, nil)
+				require.NoError(t, err)
+
+				return eachDocument(ctx, cursor, func(doc struct{ N int }) error {
+					delivered = append(delivered, doc.N)
+					if len(delivered) == tCase.failAfter {
+						return networkErr
+					}
+					return nil
+				})
+			})
+
+			_, err := query(context.TODO(), nil)
+
+			if tCase.failAfter > 0 {
+				var cmdErr mongo.CommandError
+				assert.ErrorAs(t, err, &cmdErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tCase.wantAttempts, attempts)
+			assert.Equal(t, tCase.wantDelivered, delivered)
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	each := func(send func(int) error) CountFunc {
+		return func(ctx context.Context, c *mongo.Client) (*int64, error) {
+			var count int64
+			for _, doc := range []int{1, 2} {
+				if err := send(doc); err != nil {
+					return &count, err
+				}
+				count++
+			}
+			return &count, nil
+		}
+	}
+
+	collect := func(docs <-chan int) []int {
+		var got []int
+		for doc := range docs {
+			got = append(got, doc)
+		}
+		return got
+	}
+
+	t.Run("closed when the query is never executed", func(t *testing.T) {
+		unavailable := errors.New("unavailable")
+
+		docs, wait := stream(context.TODO(), func(ctx context.Context, query func(context.Context, *mongo.Client) (*int64, error)) (*int64, error) {
+			return nil, unavailable
+		}, each)
+
+		assert.Empty(t, collect(docs))
+		_, err := wait()
+		assert.ErrorIs(t, err, unavailable)
+	})
+
+	t.Run("closed once when the query is executed again", func(t *testing.T) {
+		docs, wait := stream(context.TODO(), func(ctx context.Context, query func(context.Context, *mongo.Client) (*int64, error)) (*int64, error) {
+			if _, err := query(ctx, nil); err != nil {
+				return nil, err
+			}
+			return query(ctx, nil)
+		}, each)
+
+		assert.Equal(t, []int{1, 2, 1, 2}, collect(docs))
+		count, err := wait()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("stops when ctx is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+
+		docs, wait := stream(ctx, func(ctx context.Context, query func(context.Context, *mongo.Client) (*int64, error)) (*int64, error) {
+			return query(ctx, nil)
+		}, each)
+
+		assert.Equal(t, 1, <-docs)
+		cancel()
+
+		count, err := wait()
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, int64(1), count)
+		assert.Empty(t, collect(docs))
+	})
+}
@@ -0,0 +1,283 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FindAndModifyOptions configures DoFindOneAndUpdate, DoFindOneAndReplace and DoFindOneAndDelete.
+type FindAndModifyOptions struct {
+	// ReturnDocument chooses between the document before or after the change, before by
+	// default. Ignored by deletes.
+	ReturnDocument options.ReturnDocument
+	// Upsert inserts a document when the filter matches nothing. Ignored by deletes.
+	Upsert bool
+	// Sort chooses the document modified when the filter matches several, see WithSort.
+	Sort []string
+	// Projection sets the fields returned. When nil it is inferred from the bson tags of T.
+	Projection any
+	// Version is the version the document must be at for DoFindOneAndUpdate to change it, when
+	// T has a version field. A *ConflictError is returned otherwise.
+	Version *int64
+}
+
+func (o *FindAndModifyOptions) sort() any {
+	if o == nil || len(o.Sort) == 0 {
+		return nil
+	}
+	return sortDocument(o.Sort)
+}
+
+func findAndModifyProjection[T any](o *FindAndModifyOptions) any {
+	if o != nil && o.Projection != nil {
+		return o.Projection
+	}
+	return projectionOf[T]()
+}
+
+// DoFindOneAndUpdate applies update to the first document matching filter and returns it
+// decoded as T, or ErrNotFound. The version and updated timestamp of T, if any, are updated too.
+func DoFindOneAndUpdate[T any](db, col string, filter, update any, opts *FindAndModifyOptions) DocumentFunc[T] {
+	return Instrument(Operation{Name: "findOneAndUpdate", Database: db, Collection: col}, func(ctx context.Context, c *mongo.Client) (*T, error) {
+		if err := validateUpdate(update); err != nil {
+			return nil, err
+		}
+
+		change := update
+		if err := beforeUpdate(ctx, &change); err != nil {
+			return nil, err
+		}
+
+		change, err := prepareUpdate[T](change)
+		if err != nil {
+			return nil, err
+		}
+
+		filter := visibleFilter(ctx, db, col, filter)
+		query := filter
+		vf, versioned := versionFieldOf[T]()
+		if versioned && opts != nil && opts.Version != nil {
+			query = vf.filter(filter, *opts.Version)
+		}
+
+		fo := options.FindOneAndUpdate()
+		if opts != nil {
+			fo.SetReturnDocument(opts.ReturnDocument).SetUpsert(opts.Upsert)
+		}
+		if sort := opts.sort(); sort != nil {
+			fo.SetSort(sort)
+		}
+		if projection := findAndModifyProjection[T](opts); projection != nil {
+			fo.SetProjection(projection)
+		}
+
+		coll := c.Database(db).Collection(col)
+
+		// Like replaces, an upsert which did not match the version tries to insert the same _id again.
+		doc, err := decodeSingleResult(ctx, coll.FindOneAndUpdate(ctx, query, change, fo), afterFind[T])
+		if versioned && opts != nil && opts.Version != nil && (errors.Is(err, ErrNotFound) || errors.Is(err, ErrDuplicateKey)) {
+			if conflict := vf.conflict(ctx, coll, filter, *opts.Version); conflict != nil {
+				return nil, conflict
+			}
+		}
+
+		return doc, err
+	})
+}
+
+// DoFindOneAndReplace replaces the first document matching filter and returns it decoded as T,
+// or ErrNotFound. Versions are checked and incremented like in DoReplaceOne.
+func DoFindOneAndReplace[T any](db, col string, filter any, replacement T, opts *FindAndModifyOptions) DocumentFunc[T] {
+	return Instrument(Operation{Name: "findOneAndReplace", Database: db, Collection: col}, func(ctx context.Context, c *mongo.Client) (*T, error) {
+		if err := validateReplacement(replacement); err != nil {
+			return nil, err
+		}
+
+		fo := options.FindOneAndReplace()
+		if opts != nil {
+			fo.SetReturnDocument(opts.ReturnDocument).SetUpsert(opts.Upsert)
+		}
+		if sort := opts.sort(); sort != nil {
+			fo.SetSort(sort)
+		}
+		if projection := findAndModifyProjection[T](opts); projection != nil {
+			fo.SetProjection(projection)
+		}
+
+		coll := c.Database(db).Collection(col)
+		filter := visibleFilter(ctx, db, col, filter)
+		doc := stampReplace(replacement)
+		if err := beforeUpdate(ctx, &doc); err != nil {
+			return nil, err
+		}
+
+		vf, versioned := versionFieldOf[T]()
+		if !versioned {
+			return decodeSingleResult(ctx, coll.FindOneAndReplace(ctx, filter, doc, fo), afterFind[T])
+		}
+
+		doc, expected := bumpVersion(vf, doc)
+
+		result, err := decodeSingleResult(ctx, coll.FindOneAndReplace(ctx, vf.filter(filter, expected), doc, fo), afterFind[T])
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrDuplicateKey) {
+			if conflict := vf.conflict(ctx, coll, filter, expected); conflict != nil {
+				return nil, conflict
+			}
+		}
+
+		return result, err
+	})
+}
+
+// DoFindOneAndDelete deletes the first document matching filter and returns it decoded as T,
+// or ErrNotFound. In collections with soft delete enabled the document is marked as deleted
+// instead, and returned as it was before.
+func DoFindOneAndDelete[T any](db, col string, filter any, opts *FindAndModifyOptions) DocumentFunc[T] {
+	return Instrument(Operation{Name: "findOneAndDelete", Database: db, Collection: col}, func(ctx context.Context, c *mongo.Client) (*T, error) {
+		var (
+			sort       = opts.sort()
+			projection = findAndModifyProjection[T](opts)
+			coll       = c.Database(db).Collection(col)
+		)
+
+		if softDeleteEnabled(db, col) {
+			fo := options.FindOneAndUpdate()
+			if sort != nil {
+				fo.SetSort(sort)
+			}
+			if projection != nil {
+				fo.SetProjection(projection)
+			}
+
+			return decodeSingleResult(ctx, coll.FindOneAndUpdate(ctx, notDeleted(orEmptyFilter(filter)), softDeleteUpdate(ctx), fo), afterDelete[T])
+		}
+
+		fo := options.FindOneAndDelete()
+		if sort != nil {
+			fo.SetSort(sort)
+		}
+		if projection != nil {
+			fo.SetProjection(projection)
+		}
+
+		return decodeSingleResult(ctx, coll.FindOneAndDelete(ctx, filter, fo), afterDelete[T])
+	})
+}
+
+// decodeSingleResult decodes the document of sr and calls the hook after on it.
+func decodeSingleResult[T any](ctx context.Context, sr *mongo.SingleResult, after func(context.Context, *T) error) (*T, error) {
+	var result T
+	if err := sr.Decode(&result); err != nil {
+		return nil, translateError(err)
+	}
+
+	if err := after(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// projectionOf returns a projection including only the fields of T, or nil when T is not a
+// struct. _id is excluded when T has no field for it.
+func projectionOf[T any]() bson.D {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields, ok := bsonFieldNames(t)
+	if !ok || len(fields) == 0 {
+		return nil
+	}
+
+	projection := make(bson.D, 0, len(fields)+1)
+	hasID := false
+	for _, field := range fields {
+		hasID = hasID || field == "_id"
+		projection = append(projection, bson.E{Key: field, Value: 1})
+	}
+
+	if !hasID {
+		projection = append(projection, bson.E{Key: "_id", Value: 0})
+	}
+
+	return projection
+}
+
+// bsonFieldNames returns the names the bson codec uses for the exported fields of t,
+// flattening inline structs. It returns false when t has an inline map, which holds arbitrary
+// fields no projection can describe.
+func bsonFieldNames(t reflect.Type) ([]string, bool) {
+	var names []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, inline, skip := parseBSONTag(field)
+		if skip {
+			continue
+		}
+
+		if inline {
+			ft := field.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() != reflect.Struct {
+				return nil, false
+			}
+
+			inlined, ok := bsonFieldNames(ft)
+			if !ok {
+				return nil, false
+			}
+			names = append(names, inlined...)
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	return names, true
+}
+
+// parseBSONTag returns the key of field following the rules of the bson codec: the tag name,
+// or the field name lowercased.
+func parseBSONTag(field reflect.StructField) (name string, inline, skip bool) {
+	tag, ok := field.Tag.Lookup("bson")
+	if !ok && !strings.Contains(string(field.Tag), ":") && field.Tag != "" {
+		tag = string(field.Tag)
+	}
+
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	for _, opt := range parts[1:] {
+		if opt == "inline" {
+			inline = true
+		}
+	}
+
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+
+	return name, inline, false
+}
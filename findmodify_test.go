@@ -0,0 +1,60 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type auditFields struct {
+	CreatedBy string `bson:"created_by"`
+}
+
+type order struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	Total    float64            `bson:"total"`
+	Status   string
+	Internal string      `bson:"-"`
+	Audit    auditFields `bson:",inline"`
+}
+
+func TestProjectionOf(t *testing.T) {
+	assert.Equal(t, bson.D{
+		{Key: "_id", Value: 1},
+		{Key: "total", Value: 1},
+		{Key: "status", Value: 1},
+		{Key: "created_by", Value: 1},
+	}, projectionOf[order](), "untagged fields are lowercased, inline fields flattened and skipped fields ignored")
+
+	assert.Equal(t, bson.D{
+		{Key: "name", Value: 1},
+		{Key: "surname", Value: 1},
+		{Key: "age", Value: 1},
+		{Key: "salary", Value: 1},
+		{Key: "salary_dot_0", Value: 1},
+		{Key: "fav_numbers", Value: 1},
+		{Key: "best_day_ever", Value: 1},
+		{Key: "_id", Value: 0},
+	}, projectionOf[Person](), "_id is excluded when the type has no field for it")
+
+	assert.Equal(t, projectionOf[order](), projectionOf[*order]())
+	assert.Nil(t, projectionOf[bson.M]())
+	assert.Nil(t, projectionOf[struct {
+		Name  string `bson:"name"`
+		Extra bson.M `bson:",inline"`
+	}]())
+}
+
+func TestFindAndModifyOptions(t *testing.T) {
+	var opts *FindAndModifyOptions
+
+	assert.Nil(t, opts.sort())
+	assert.Equal(t, projectionOf[order](), findAndModifyProjection[order](opts))
+
+	opts = &FindAndModifyOptions{Sort: []string{"-total"}, Projection: bson.D{{Key: "total", Value: 1}}}
+
+	assert.Equal(t, bson.D{{Key: "total", Value: -1}}, opts.sort())
+	assert.Equal(t, bson.D{{Key: "total", Value: 1}}, findAndModifyProjection[order](opts))
+}
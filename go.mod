@@ -2,7 +2,10 @@ module github.com/MrTimeout/go-mongo
 
 go 1.18
 
-require go.mongodb.org/mongo-driver v1.10.1
+require (
+	github.com/stretchr/testify v1.8.0
+	go.mongodb.org/mongo-driver v1.10.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -11,7 +14,6 @@ require (
 	github.com/montanaflynn/stats v0.0.0-20171201202039-1bf9dbcd8cbe // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.8.0 // indirect
 	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
 	github.com/xdg-go/scram v1.1.1 // indirect
 	github.com/xdg-go/stringprep v1.0.3 // indirect
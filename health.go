@@ -0,0 +1,70 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// ConnState is the state of a registered client.
+type ConnState int
+
+const (
+	// StateIdle means the client was never connected, or the last attempt failed.
+	StateIdle ConnState = iota
+	// StateConnecting means a connection attempt is in progress.
+	StateConnecting
+	// StateConnected means the last ping succeeded.
+	StateConnected
+	// StateUnavailable means the client is connected but the last health check failed.
+	StateUnavailable
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateUnavailable:
+		return "unavailable"
+	}
+	return "unknown"
+}
+
+// healthCheck pings the server every interval until ctx is cancelled, updating the state
+// of the entry. The driver reconnects by itself, we only keep track of it.
+func (e *registryEntry) healthCheck(ctx context.Context, cli *mongo.Client) {
+	defer close(e.healthDone)
+
+	ticker := time.NewTicker(e.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pctx, cl := context.WithTimeout(ctx, e.cfg.HealthCheckInterval)
+		err := cli.Ping(pctx, readpref.Primary())
+		cl()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		e.mu.Lock()
+		if err != nil {
+			e.setState(StateUnavailable)
+		} else {
+			e.setState(StateConnected)
+		}
+		e.mu.Unlock()
+	}
+}
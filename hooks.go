@@ -0,0 +1,146 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Hooks let document types take part in their persistence. They are looked up on a pointer
+// to the document, so they can be implemented with pointer receivers to modify it. An error
+// returned by a hook aborts the operation.
+type (
+	// BeforeInserter is called before a document is inserted by DoInsert, DoInsertChunked or
+	// an InsertModel of DoBulkWrite, after its timestamps are set. A failure in
+	// DoInsertChunked only aborts its chunk.
+	BeforeInserter interface {
+		BeforeInsert(ctx context.Context) error
+	}
+
+	// AfterFinder is called on every document decoded by the find helpers.
+	AfterFinder interface {
+		AfterFind(ctx context.Context) error
+	}
+
+	// BeforeUpdater is called on the update document of DoFindAndUpdate, DoFindOneAndUpdate,
+	// DoUpdateOneOf, DoUpdateManyOf, Repository.Update and UpdateModelOf, and on the
+	// replacement of DoReplaceOne, DoFindOneAndReplace and ReplaceModel.
+	BeforeUpdater interface {
+		BeforeUpdate(ctx context.Context) error
+	}
+
+	// AfterDeleter is called on the document removed by DoFindOneAndDelete.
+	AfterDeleter interface {
+		AfterDelete(ctx context.Context) error
+	}
+)
+
+// callHook calls hook with doc, a pointer to a document, when it implements H, trying the
+// pointer first and then the value it points to. A document held by an interface, like the
+// ones of Store.Insert, is not addressable: the hook is called on a copy, stored back after.
+func callHook[H any](doc any, hook func(H) error) error {
+	if h, ok := doc.(H); ok {
+		return hook(h)
+	}
+
+	v := reflect.ValueOf(doc)
+	if v.Kind() != reflect.Pointer || v.IsNil() || !v.Elem().CanInterface() {
+		return nil
+	}
+
+	elem := v.Elem()
+	if elem.Kind() == reflect.Interface {
+		if elem.IsNil() {
+			return nil
+		}
+		if h, ok := elem.Interface().(H); ok {
+			return hook(h)
+		}
+
+		copied := reflect.New(elem.Elem().Type())
+		copied.Elem().Set(elem.Elem())
+		h, ok := copied.Interface().(H)
+		if !ok {
+			return nil
+		}
+		if err := hook(h); err != nil {
+			return err
+		}
+
+		elem.Set(copied.Elem())
+		return nil
+	}
+
+	if h, ok := elem.Interface().(H); ok {
+		return hook(h)
+	}
+
+	return nil
+}
+
+func beforeInsert[T any](ctx context.Context, doc *T) error {
+	return callHook(doc, func(h BeforeInserter) error {
+		if err := h.BeforeInsert(ctx); err != nil {
+			return fmt.Errorf("BeforeInsert: %w", err)
+		}
+		return nil
+	})
+}
+
+func afterFind[T any](ctx context.Context, doc *T) error {
+	return callHook(doc, func(h AfterFinder) error {
+		if err := h.AfterFind(ctx); err != nil {
+			return fmt.Errorf("AfterFind: %w", err)
+		}
+		return nil
+	})
+}
+
+func beforeUpdate[T any](ctx context.Context, doc *T) error {
+	return callHook(doc, func(h BeforeUpdater) error {
+		if err := h.BeforeUpdate(ctx); err != nil {
+			return fmt.Errorf("BeforeUpdate: %w", err)
+		}
+		return nil
+	})
+}
+
+func afterDelete[T any](ctx context.Context, doc *T) error {
+	return callHook(doc, func(h AfterDeleter) error {
+		if err := h.AfterDelete(ctx); err != nil {
+			return fmt.Errorf("AfterDelete: %w", err)
+		}
+		return nil
+	})
+}
+
+// afterFindAll calls AfterFind on every element of docs.
+func afterFindAll[T any](ctx context.Context, docs []T) error {
+	for i := range docs {
+		if err := afterFind(ctx, &docs[i]); err != nil {
+			return fmt.Errorf("document %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// afterFindResult calls AfterFind on the documents decoded into result, a pointer to a slice
+// or to a single document.
+func afterFindResult(ctx context.Context, result any) error {
+	v := reflect.ValueOf(result)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return nil
+	}
+
+	if elems := v.Elem(); elems.Kind() == reflect.Slice {
+		for i := 0; i < elems.Len(); i++ {
+			doc := elems.Index(i).Addr().Interface()
+			if err := afterFind(ctx, &doc); err != nil {
+				return fmt.Errorf("document %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+
+	return afterFind(ctx, &result)
+}
@@ -0,0 +1,115 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type hooked struct {
+	Name  string `bson:"name"`
+	Found bool   `bson:"-"`
+}
+
+func (h *hooked) BeforeInsert(ctx context.Context) error {
+	if h.Name == "" {
+		return errors.New("name is required")
+	}
+	h.Name = strings.ToLower(h.Name)
+	return nil
+}
+
+func (h *hooked) AfterFind(ctx context.Context) error {
+	h.Found = true
+	return nil
+}
+
+type valueHooked struct{}
+
+func (valueHooked) BeforeUpdate(ctx context.Context) error { return errors.New("read only") }
+
+type hookedUpdate struct {
+	Set bson.M `bson:"$set"`
+}
+
+func (hookedUpdate) BeforeUpdate(ctx context.Context) error { return errors.New("read only") }
+
+func TestHooks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("pointer receivers can change the document", func(t *testing.T) {
+		doc := hooked{Name: "ANA"}
+		assert.NoError(t, beforeInsert(ctx, &doc))
+		assert.Equal(t, "ana", doc.Name)
+
+		assert.ErrorContains(t, beforeInsert(ctx, &hooked{}), "BeforeInsert: name is required")
+	})
+
+	t.Run("documents which are pointers", func(t *testing.T) {
+		doc := &hooked{}
+		assert.NoError(t, afterFind(ctx, &doc))
+		assert.True(t, doc.Found)
+	})
+
+	t.Run("value receivers", func(t *testing.T) {
+		assert.Error(t, beforeUpdate(ctx, &valueHooked{}))
+
+		var doc any = valueHooked{}
+		assert.Error(t, beforeUpdate(ctx, &doc), "documents passed as any are checked too")
+	})
+
+	t.Run("pointer receivers of documents passed as any", func(t *testing.T) {
+		var doc any = hooked{Name: "ANA"}
+		assert.NoError(t, beforeInsert(ctx, &doc))
+		assert.Equal(t, hooked{Name: "ana"}, doc, "the change is stored back")
+
+		var invalid any = hooked{}
+		assert.ErrorContains(t, beforeInsert(ctx, &invalid), "name is required")
+	})
+
+	t.Run("bulk write models", func(t *testing.T) {
+		_, _, err := toWriteModels(ctx, "db", "col", []WriteModel{InsertModel[hooked]{Document: hooked{}}})
+		assert.ErrorContains(t, err, "model 0: BeforeInsert: name is required")
+
+		_, _, err = toWriteModels(ctx, "db", "col", []WriteModel{ReplaceModel[valueHooked]{Filter: Person{}, Replacement: valueHooked{}}})
+		assert.ErrorContains(t, err, "BeforeUpdate: read only")
+
+		writes, _, err := toWriteModels(ctx, "db", "col", []WriteModel{InsertModel[hooked]{Document: hooked{Name: "ANA"}}})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "ana", writes[0].(*mongo.InsertOneModel).Document.(bson.Raw).Lookup("name").StringValue())
+		}
+	})
+
+	t.Run("updates", func(t *testing.T) {
+		update := hookedUpdate{Set: bson.M{"name": "ana"}}
+
+		_, err := DoFindOneAndUpdate[Person]("db", "col", Person{}, update, nil)(ctx, nil)
+		assert.ErrorContains(t, err, "BeforeUpdate: read only")
+
+		_, _, err = toWriteModels(ctx, "db", "col", []WriteModel{UpdateModelOf[Person]{Filter: Person{}, Update: update}})
+		assert.ErrorContains(t, err, "BeforeUpdate: read only")
+	})
+
+	t.Run("types without hooks", func(t *testing.T) {
+		assert.NoError(t, afterDelete(ctx, &Person{}))
+	})
+
+	t.Run("find results", func(t *testing.T) {
+		docs := []hooked{{Name: "a"}, {Name: "b"}}
+		assert.NoError(t, afterFindResult(ctx, &docs))
+		assert.True(t, docs[0].Found && docs[1].Found)
+
+		one := &hooked{}
+		assert.NoError(t, afterFindResult(ctx, one))
+		assert.True(t, one.Found)
+
+		items := []hooked{{}}
+		assert.NoError(t, afterFindAll(ctx, items))
+		assert.True(t, items[0].Found)
+	})
+}
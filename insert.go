@@ -0,0 +1,212 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Defaults of InsertChunkedOptions.
+const (
+	DefaultChunkSize         = 1000
+	DefaultInsertConcurrency = 4
+)
+
+// ErrPartialInsert is matched by the error returned by DoInsertChunked when some chunks failed.
+var ErrPartialInsert = errors.New("some documents were not inserted")
+
+// InsertChunkedOptions configures DoInsertChunked.
+type InsertChunkedOptions struct {
+	// ChunkSize is the number of documents sent in each InsertMany, DefaultChunkSize if not set.
+	ChunkSize int
+	// Concurrency is the maximum number of chunks sent at the same time,
+	// DefaultInsertConcurrency if not set.
+	Concurrency int
+	// Progress is called after every chunk with the number of documents processed so far and
+	// the total. Calls never overlap.
+	Progress func(done, total int)
+}
+
+func (o *InsertChunkedOptions) chunkSize() int {
+	if o == nil || o.ChunkSize <= 0 {
+		return DefaultChunkSize
+	}
+	return o.ChunkSize
+}
+
+func (o *InsertChunkedOptions) concurrency() int {
+	if o == nil || o.Concurrency <= 0 {
+		return DefaultInsertConcurrency
+	}
+	return o.Concurrency
+}
+
+// InsertChunkedResult is the result of DoInsertChunked.
+type InsertChunkedResult struct {
+	// InsertedIDs are the _id of the documents in input order, nil for the ones not inserted.
+	InsertedIDs []any
+	// Failures are the chunks with documents not inserted, also returned as an *InsertError.
+	Failures []*ChunkFailure
+}
+
+// ChunkFailure reports the documents of a chunk which were not inserted.
+type ChunkFailure struct {
+	// Chunk is the position of the chunk, the documents from Chunk*ChunkSize on.
+	Chunk int
+	// Indices are the positions in the input of the documents not inserted.
+	Indices []int
+	// Err is the error of the chunk, translated like the ones of DoInsert.
+	Err error
+}
+
+func (f *ChunkFailure) Error() string {
+	return fmt.Sprintf("chunk %d, %d documents: %v", f.Chunk, len(f.Indices), f.Err)
+}
+
+func (f *ChunkFailure) Unwrap() error { return f.Err }
+
+// InsertError is returned by DoInsertChunked when some documents were not inserted. It matches
+// ErrPartialInsert and any error matched by one of the failures.
+type InsertError struct {
+	Failures []*ChunkFailure
+}
+
+func (e *InsertError) Error() string {
+	if len(e.Failures) == 1 {
+		return fmt.Sprintf("%v: %v", ErrPartialInsert, e.Failures[0])
+	}
+	return fmt.Sprintf("%v: %d chunks failed, first %v", ErrPartialInsert, len(e.Failures), e.Failures[0])
+}
+
+func (e *InsertError) Is(target error) bool {
+	if target == ErrPartialInsert {
+		return true
+	}
+
+	for _, f := range e.Failures {
+		if errors.Is(f, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InsertChunkedFunc returns the result of a chunked insert.
+type InsertChunkedFunc func(context.Context, *mongo.Client) (*InsertChunkedResult, error)
+
+// DoInsertChunked inserts docs splitting them in chunks sent concurrently, so slices of any
+// size can be inserted without building a single huge command. Documents are inserted
+// unordered: a failed document does not stop the rest of its chunk.
+//
+// The chunks run in their own goroutines, so it must not be used inside a transaction.
+func DoInsertChunked[T any](db, col string, docs []T, opts *InsertChunkedOptions) InsertChunkedFunc {
+	return Instrument(Operation{Name: "insertChunked", Database: db, Collection: col}, func(ctx context.Context, c *mongo.Client) (*InsertChunkedResult, error) {
+		var (
+			result    = &InsertChunkedResult{InsertedIDs: make([]any, len(docs))}
+			coll      = c.Database(db).Collection(col)
+			chunkSize = opts.chunkSize()
+			sem       = make(chan struct{}, opts.concurrency())
+			wg        sync.WaitGroup
+			mtx       sync.Mutex
+			done      int
+		)
+
+		for chunk, start := 0, 0; start < len(docs); chunk, start = chunk+1, start+chunkSize {
+			end := start + chunkSize
+			if end > len(docs) {
+				end = len(docs)
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				mtx.Lock()
+				result.Failures = append(result.Failures, chunkFailure(chunk, start, end, ctx.Err()))
+				mtx.Unlock()
+				continue
+			}
+
+			wg.Add(1)
+			go func(chunk, start, end int) {
+				defer func() { <-sem; wg.Done() }()
+
+				ids, failure := insertChunk(ctx, coll, docs[start:end], chunk, start)
+
+				mtx.Lock()
+				defer mtx.Unlock()
+
+				copy(result.InsertedIDs[start:end], ids)
+				if failure != nil {
+					result.Failures = append(result.Failures, failure)
+				}
+
+				done += end - start
+				if opts != nil && opts.Progress != nil {
+					opts.Progress(done, len(docs))
+				}
+			}(chunk, start, end)
+		}
+
+		wg.Wait()
+
+		if len(result.Failures) == 0 {
+			return result, nil
+		}
+
+		sort.Slice(result.Failures, func(i, j int) bool { return result.Failures[i].Chunk < result.Failures[j].Chunk })
+
+		return result, &InsertError{Failures: result.Failures}
+	})
+}
+
+// insertChunk inserts chunk, the documents from start on, and returns their ids, nil for the
+// ones not inserted.
+func insertChunk[T any](ctx context.Context, coll *mongo.Collection, docs []T, chunk, start int) ([]any, *ChunkFailure) {
+	input := make([]interface{}, len(docs))
+	for i := range docs {
+		doc := stampInsert(docs[i])
+		if err := beforeInsert(ctx, &doc); err != nil {
+			return nil, chunkFailure(chunk, start, start+len(docs), fmt.Errorf("document %d: %w", start+i, err))
+		}
+		input[i] = doc
+	}
+
+	imr, err := coll.InsertMany(ctx, input, options.InsertMany().SetOrdered(false))
+	if err == nil {
+		return imr.InsertedIDs, nil
+	}
+
+	var bwe mongo.BulkWriteException
+	if imr == nil || !errors.As(err, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0 {
+		return nil, chunkFailure(chunk, start, start+len(docs), translateError(err))
+	}
+
+	ids := imr.InsertedIDs
+	failure := &ChunkFailure{Chunk: chunk, Indices: make([]int, 0, len(bwe.WriteErrors)), Err: err}
+	typed := false
+	for _, we := range bwe.WriteErrors {
+		ids[we.Index] = nil
+		failure.Indices = append(failure.Indices, start+we.Index)
+
+		// Report the first typed error, with its index in the whole input.
+		if typedErr := typedWriteError(start+we.Index, we.WriteError, err); typedErr != nil && !typed {
+			failure.Err, typed = typedErr, true
+		}
+	}
+
+	return ids, failure
+}
+
+func chunkFailure(chunk, start, end int, err error) *ChunkFailure {
+	failure := &ChunkFailure{Chunk: chunk, Indices: make([]int, 0, end-start), Err: err}
+	for i := start; i < end; i++ {
+		failure.Indices = append(failure.Indices, i)
+	}
+	return failure
+}
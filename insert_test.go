@@ -0,0 +1,32 @@
+package mongodb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertChunkedOptions(t *testing.T) {
+	var opts *InsertChunkedOptions
+
+	assert.Equal(t, DefaultChunkSize, opts.chunkSize())
+	assert.Equal(t, DefaultInsertConcurrency, opts.concurrency())
+
+	opts = &InsertChunkedOptions{ChunkSize: 10, Concurrency: 1}
+
+	assert.Equal(t, 10, opts.chunkSize())
+	assert.Equal(t, 1, opts.concurrency())
+}
+
+func TestInsertError(t *testing.T) {
+	failure := chunkFailure(2, 20, 23, &DuplicateKeyError{Index: 21})
+
+	assert.Equal(t, []int{20, 21, 22}, failure.Indices)
+
+	err := error(&InsertError{Failures: []*ChunkFailure{failure}})
+
+	assert.ErrorIs(t, err, ErrPartialInsert)
+	assert.ErrorIs(t, err, ErrDuplicateKey)
+	assert.False(t, errors.Is(err, ErrValidation))
+}
@@ -0,0 +1,93 @@
+package mongodb
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Operation describes the query being executed by a middleware chain.
+type Operation struct {
+	Name       string
+	Database   string
+	Collection string
+	// Idempotent is true when running the operation twice has the same effect as running it
+	// once, so it is safe to retry after an ambiguous failure.
+	Idempotent bool
+}
+
+// QueryFunc is a query whose result has already been captured, as seen by middlewares.
+type QueryFunc func(ctx context.Context, c *mongo.Client) error
+
+// Middleware wraps a query, running code before and after it.
+type Middleware func(next QueryFunc) QueryFunc
+
+type operationKey struct{}
+
+var (
+	middlewares    []Middleware
+	middlewaresMtx sync.RWMutex
+)
+
+// Use appends middlewares to the chain run around every instrumented query. The first
+// middleware registered is the outermost one.
+func Use(mw ...Middleware) {
+	middlewaresMtx.Lock()
+	defer middlewaresMtx.Unlock()
+
+	middlewares = append(middlewares, mw...)
+}
+
+// ClearMiddlewares removes every middleware registered with Use.
+func ClearMiddlewares() {
+	middlewaresMtx.Lock()
+	defer middlewaresMtx.Unlock()
+
+	middlewares = nil
+}
+
+// OperationFromContext returns the operation a middleware is wrapping.
+func OperationFromContext(ctx context.Context) (Operation, bool) {
+	op, ok := ctx.Value(operationKey{}).(Operation)
+	return op, ok
+}
+
+// Instrument runs query through the middleware chain. Every Do* function is already
+// instrumented; use it for custom queries passed to DialConnection.
+func Instrument[T any](op Operation, query func(context.Context, *mongo.Client) (*T, error)) func(context.Context, *mongo.Client) (*T, error) {
+	return func(ctx context.Context, c *mongo.Client) (*T, error) {
+		var result *T
+
+		next := QueryFunc(func(ctx context.Context, c *mongo.Client) error {
+			var err error
+			result, err = query(ctx, c)
+			return err
+		})
+
+		middlewaresMtx.RLock()
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		middlewaresMtx.RUnlock()
+
+		err := next(context.WithValue(ctx, operationKey{}, op), c)
+		return result, err
+	}
+}
+
+// Observe returns a middleware calling fn after every query with its error and duration.
+func Observe(fn func(ctx context.Context, op Operation, err error, elapsed time.Duration)) Middleware {
+	return func(next QueryFunc) QueryFunc {
+		return func(ctx context.Context, c *mongo.Client) error {
+			start := time.Now()
+			err := next(ctx, c)
+
+			op, _ := OperationFromContext(ctx)
+			fn(ctx, op, err, time.Since(start))
+
+			return err
+		}
+	}
+}
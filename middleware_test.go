@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	t.Cleanup(ClearMiddlewares)
+
+	var (
+		calls    []string
+		observed Operation
+		gotErr   error
+	)
+
+	trace := func(name string) Middleware {
+		return func(next QueryFunc) QueryFunc {
+			return func(ctx context.Context, c *mongo.Client) error {
+				calls = append(calls, "before "+name)
+				err := next(ctx, c)
+				calls = append(calls, "after "+name)
+				return err
+			}
+		}
+	}
+
+	Use(trace("outer"), trace("inner"))
+	Use(Observe(func(ctx context.Context, op Operation, err error, elapsed time.Duration) {
+		observed, gotErr = op, err
+	}))
+
+	wantErr := errors.New("boom")
+	op := Operation{Name: "find", Database: "testing", Collection: "people"}
+	query := Instrument(op, func(ctx context.Context, c *mongo.Client) (*int, error) {
+		calls = append(calls, "query")
+
+		got, ok := OperationFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, op, got)
+
+		n := 3
+		return &n, wantErr
+	})
+
+	result, err := query(context.TODO(), nil)
+
+	assert.Equal(t, 3, *result)
+	assert.ErrorIs(t, err, wantErr)
+	assert.Equal(t, []string{"before outer", "before inner", "query", "after inner", "after outer"}, calls)
+	assert.Equal(t, op, observed)
+	assert.ErrorIs(t, gotErr, wantErr)
+}
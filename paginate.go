@@ -0,0 +1,351 @@
+package mongodb
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+
+	o "github.com/MrTimeout/go-mongo/operator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultPageSize is used when PageRequest.Size is not set.
+const DefaultPageSize = 20
+
+// ErrInvalidToken is returned when a continuation token was tampered with, signed with another
+// secret or built for another sort.
+var ErrInvalidToken = errors.New("invalid page token")
+
+var (
+	paginationSecret    []byte
+	paginationSecretMtx sync.RWMutex
+)
+
+func init() {
+	paginationSecret = make([]byte, 32)
+	if _, err := rand.Read(paginationSecret); err != nil {
+		panic(err)
+	}
+}
+
+// SetPaginationSecret sets the key used to sign page tokens. By default a random key is
+// generated on start, so tokens are only valid in the process which created them; set the
+// same secret on every instance behind a load balancer.
+func SetPaginationSecret(secret []byte) {
+	paginationSecretMtx.Lock()
+	defer paginationSecretMtx.Unlock()
+
+	paginationSecret = append([]byte(nil), secret...)
+}
+
+// PageRequest describes the page to fetch.
+type PageRequest struct {
+	// Keys is the sort of the pages, "_id" if empty. A key prefixed with "-" is sorted
+	// descending. "_id" is appended when missing so the order is always total. There has to
+	// be an index on the keys.
+	Keys []string
+	// Size is the maximum number of documents of the page.
+	Size int64
+	// Token is Page.Next or Page.Previous of the page already fetched, empty for the first page.
+	Token string
+}
+
+// Page is a page of documents and the tokens to reach its neighbours. Tokens are empty when
+// there is nothing else in that direction.
+type Page[T any] struct {
+	Items    []T
+	Next     string
+	Previous string
+}
+
+// PageFunc returns a page of documents decoded as T.
+type PageFunc[T any] func(context.Context, *mongo.Client) (*Page[T], error)
+
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+// pageToken is the content of a continuation token: the values of the sort keys of the
+// first or last document of a page.
+type pageToken struct {
+	Backward bool   `bson:"b"`
+	Keys     string `bson:"k"`
+	Values   bson.A `bson:"v"`
+}
+
+// Paginate returns the page described by req of the documents matching filter, using keyset
+// pagination: the next page starts after the last key seen instead of skipping documents.
+func Paginate[T any](db, col string, filter bson.D, req PageRequest) PageFunc[T] {
+	return Instrument(Operation{Name: "paginate", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*Page[T], error) {
+		keys := parseSortKeys(req.Keys)
+		size := req.Size
+		if size <= 0 {
+			size = DefaultPageSize
+		}
+
+		var token *pageToken
+		if req.Token != "" {
+			t, err := decodePageToken(req.Token, keys)
+			if err != nil {
+				return nil, err
+			}
+			token = t
+		}
+
+		backward := token != nil && token.Backward
+
+		query := filter
+		if token != nil {
+			query = andFilter(filter, keysetFilter(keys, token.Values, backward))
+		}
+		if query == nil {
+			query = bson.D{}
+		}
+
+		opt := options.Find().SetSort(keysetSort(keys, backward)).SetLimit(size + 1)
+
+		cursor, err := c.Database(db).Collection(col).Find(ctx, visibleFilter(ctx, db, col, query), opt)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		var raws []bson.Raw
+		if err := cursor.All(ctx, &raws); err != nil {
+			return nil, translateError(err)
+		}
+
+		hasMore := int64(len(raws)) > size
+		if hasMore {
+			raws = raws[:size]
+		}
+
+		if backward {
+			for i, j := 0, len(raws)-1; i < j; i, j = i+1, j-1 {
+				raws[i], raws[j] = raws[j], raws[i]
+			}
+		}
+
+		page := &Page[T]{Items: make([]T, len(raws))}
+		for i := range raws {
+			if err := bson.Unmarshal(raws[i], &page.Items[i]); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := afterFindAll(ctx, page.Items); err != nil {
+			return nil, err
+		}
+
+		if len(raws) == 0 {
+			return page, nil
+		}
+
+		// Going forward there is a next page if we got more documents than asked, and a
+		// previous one if we came from somewhere. Going backward it is the other way around.
+		hasNext, hasPrevious := hasMore, token != nil
+		if backward {
+			hasNext, hasPrevious = true, hasMore
+		}
+
+		if hasNext {
+			if page.Next, err = encodePageToken(keys, raws[len(raws)-1], false); err != nil {
+				return nil, err
+			}
+		}
+		if hasPrevious {
+			if page.Previous, err = encodePageToken(keys, raws[0], true); err != nil {
+				return nil, err
+			}
+		}
+
+		return page, nil
+	})
+}
+
+func parseSortKeys(fields []string) []sortKey {
+	var (
+		keys  = make([]sortKey, 0, len(fields)+1)
+		hasID bool
+	)
+
+	for _, field := range fields {
+		key := sortKey{field: strings.TrimPrefix(field, "+")}
+		if strings.HasPrefix(field, "-") {
+			key = sortKey{field: field[1:], desc: true}
+		}
+
+		hasID = hasID || key.field == "_id"
+		keys = append(keys, key)
+	}
+
+	if !hasID {
+		keys = append(keys, sortKey{field: "_id"})
+	}
+
+	return keys
+}
+
+func keysetSort(keys []sortKey, backward bool) bson.D {
+	sort := make(bson.D, len(keys))
+
+	for i, key := range keys {
+		dir := 1
+		if key.desc != backward {
+			dir = -1
+		}
+		sort[i] = bson.E{Key: key.field, Value: dir}
+	}
+
+	return sort
+}
+
+// keysetFilter matches the documents placed after values in the sort order, or before them
+// when backward is true. For keys a, b and values x, y going forward and ascending:
+//
+//	{ $or: [ { a: { $gt: x } }, { $and: [ { a: { $eq: x } }, { b: { $gt: y } } ] } ] }
+//
+// Null and missing values sort before any other, see keysetBound.
+func keysetFilter(keys []sortKey, values bson.A, backward bool) bson.D {
+	branches := make([]bson.D, 0, len(keys))
+
+	for i, key := range keys {
+		op := "$gt"
+		if key.desc != backward {
+			op = "$lt"
+		}
+
+		bound, ok := keysetBound(key.field, op, values[i])
+		if !ok {
+			continue
+		}
+
+		conds := make([]bson.D, 0, i+1)
+		for j := 0; j < i; j++ {
+			conds = append(conds, o.F(keys[j].field, bson.E{Key: "$eq", Value: values[j]}))
+		}
+		conds = append(conds, bound)
+
+		if len(conds) == 1 {
+			branches = append(branches, conds[0])
+		} else {
+			branches = append(branches, o.And(conds...))
+		}
+	}
+
+	switch len(branches) {
+	case 0:
+		// Nothing sorts before null, and _id always exists.
+		return o.F("_id", bson.E{Key: "$exists", Value: false})
+	case 1:
+		return branches[0]
+	}
+
+	return o.Or(branches...)
+}
+
+// keysetBound matches the values of field strictly after value for op, $gt or $lt. Null and
+// missing values sort first, but type bracketing keeps $gt and $lt from matching across
+// them: after null is everything not null, before null is nothing, which returns false, and
+// before any other value are the documents where field is null or missing too. _id is never
+// missing.
+func keysetBound(field, op string, value any) (bson.D, bool) {
+	switch {
+	case value == nil && op == "$gt":
+		return o.F(field, bson.E{Key: "$ne", Value: nil}), true
+	case value == nil:
+		return nil, false
+	case op == "$lt" && field != "_id":
+		return o.Or(o.F(field, bson.E{Key: op, Value: value}), bson.D{{Key: field, Value: nil}}), true
+	}
+
+	return o.F(field, bson.E{Key: op, Value: value}), true
+}
+
+func andFilter(filter, other bson.D) bson.D {
+	if len(filter) == 0 {
+		return other
+	}
+
+	return o.And(filter, other)
+}
+
+func sortSignature(keys []sortKey) string {
+	fields := make([]string, len(keys))
+	for i, key := range keys {
+		fields[i] = key.field
+		if key.desc {
+			fields[i] = "-" + key.field
+		}
+	}
+
+	return strings.Join(fields, ",")
+}
+
+func encodePageToken(keys []sortKey, doc bson.Raw, backward bool) (string, error) {
+	token := pageToken{Backward: backward, Keys: sortSignature(keys), Values: make(bson.A, len(keys))}
+
+	for i, key := range keys {
+		rv, err := doc.LookupErr(strings.Split(key.field, ".")...)
+		if err != nil {
+			// Missing fields sort as null.
+			continue
+		}
+
+		if err := rv.Unmarshal(&token.Values[i]); err != nil {
+			return "", err
+		}
+	}
+
+	payload, err := bson.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(signToken(payload)), nil
+}
+
+func decodePageToken(token string, keys []sortKey) (*pageToken, error) {
+	encPayload, encSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encSignature)
+	if err != nil || !hmac.Equal(signature, signToken(payload)) {
+		return nil, ErrInvalidToken
+	}
+
+	var t pageToken
+	if err := bson.Unmarshal(payload, &t); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if t.Keys != sortSignature(keys) || len(t.Values) != len(keys) {
+		return nil, ErrInvalidToken
+	}
+
+	return &t, nil
+}
+
+func signToken(payload []byte) []byte {
+	paginationSecretMtx.RLock()
+	defer paginationSecretMtx.RUnlock()
+
+	mac := hmac.New(sha256.New, paginationSecret)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
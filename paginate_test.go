@@ -0,0 +1,100 @@
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	o "github.com/MrTimeout/go-mongo/operator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPageToken(t *testing.T) {
+	var (
+		id        = primitive.NewObjectID()
+		createdAt = primitive.NewDateTimeFromTime(time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC))
+		keys      = parseSortKeys([]string{"-created_at"})
+		doc       = bson.Raw(bsonMustMarshal(t, bson.D{{Key: "_id", Value: id}, {Key: "created_at", Value: createdAt}, {Key: "name", Value: "x"}}))
+	)
+
+	token, err := encodePageToken(keys, doc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("round trip keeps the key values and their types", func(t *testing.T) {
+		got, err := decodePageToken(token, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.True(t, got.Backward)
+		assert.Equal(t, bson.A{createdAt, id}, got.Values)
+	})
+
+	t.Run("tampered tokens are rejected", func(t *testing.T) {
+		tampered := []byte(token)
+		tampered[3] ^= 1
+
+		_, err := decodePageToken(string(tampered), keys)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+
+		_, err = decodePageToken("garbage", keys)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("tokens are bound to the sort", func(t *testing.T) {
+		_, err := decodePageToken(token, parseSortKeys([]string{"created_at"}))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("tokens are bound to the secret", func(t *testing.T) {
+		previous := paginationSecret
+		t.Cleanup(func() { SetPaginationSecret(previous) })
+
+		SetPaginationSecret([]byte("another secret"))
+
+		_, err := decodePageToken(token, keys)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestKeysetFilter(t *testing.T) {
+	keys := parseSortKeys([]string{"-created_at"})
+
+	assert.Equal(t, []sortKey{{field: "created_at", desc: true}, {field: "_id"}}, keys, "_id is appended to break ties")
+	assert.Equal(t, bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}, keysetSort(keys, false))
+	assert.Equal(t, bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: -1}}, keysetSort(keys, true))
+
+	assert.Equal(t,
+		o.Or(
+			o.Or(o.F("created_at", bson.E{Key: "$lt", Value: 10}), bson.D{{Key: "created_at", Value: nil}}),
+			o.And(o.F("created_at", bson.E{Key: "$eq", Value: 10}), o.F("_id", bson.E{Key: "$gt", Value: 3})),
+		),
+		keysetFilter(keys, bson.A{10, 3}, false),
+		"nulls come last in a descending sort",
+	)
+
+	assert.Equal(t,
+		o.F("_id", bson.E{Key: "$lt", Value: 3}),
+		keysetFilter(parseSortKeys(nil), bson.A{3}, true),
+	)
+
+	asc := parseSortKeys([]string{"deleted_at"})
+
+	assert.Equal(t,
+		o.Or(
+			o.F("deleted_at", bson.E{Key: "$ne", Value: nil}),
+			o.And(o.F("deleted_at", bson.E{Key: "$eq", Value: nil}), o.F("_id", bson.E{Key: "$gt", Value: 3})),
+		),
+		keysetFilter(asc, bson.A{nil, 3}, false),
+		"everything not null comes after null",
+	)
+
+	assert.Equal(t,
+		o.And(o.F("deleted_at", bson.E{Key: "$eq", Value: nil}), o.F("_id", bson.E{Key: "$lt", Value: 3})),
+		keysetFilter(asc, bson.A{nil, 3}, true),
+		"nothing comes before null",
+	)
+}
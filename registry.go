@@ -0,0 +1,396 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultClientName is the name of the client used by GetMongoClient and DialConnection.
+const DefaultClientName = "default"
+
+var (
+	// ErrClientNotRegistered is returned when asking for a name nobody registered.
+	ErrClientNotRegistered = errors.New("client not registered")
+
+	// ErrClientAlreadyRegistered is returned when registering a name twice.
+	ErrClientAlreadyRegistered = errors.New("client already registered")
+
+	// ErrServerUnavailable is returned by fail fast clients while the health checker
+	// cannot reach the server.
+	ErrServerUnavailable = errors.New("server unavailable")
+
+	// ErrClientClosed is returned to callers waiting on a client which is being closed.
+	ErrClientClosed = errors.New("client closed")
+
+	// ErrShutdown is returned to queries started while the registry is shutting down.
+	ErrShutdown = errors.New("shutting down")
+)
+
+var defaultRegistry = NewRegistry()
+
+// Registry keeps a set of named clients, each one connected lazily the first time it is used.
+type Registry struct {
+	mu      sync.Mutex
+	entries map[string]*registryEntry
+
+	inflight int
+	draining bool
+	// drained is closed when the last in-flight query finishes during a shutdown.
+	drained chan struct{}
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]*registryEntry)}
+}
+
+// Register adds a client config under name. Nothing is dialed until the client is requested.
+func (r *Registry) Register(name string, cfg ClientConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.entries[name]; ok {
+		return fmt.Errorf("%w: %s", ErrClientAlreadyRegistered, name)
+	}
+
+	r.entries[name] = newRegistryEntry(cfg)
+	return nil
+}
+
+// Client returns the client registered under name, connecting to it if needed. The default
+// client is registered on demand using LoadClientConfig.
+func (r *Registry) Client(ctx context.Context, name string) (*mongo.Client, error) {
+	entry, err := r.entry(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.get(ctx)
+}
+
+// Store returns a store using the client registered under name and the database of its config.
+func (r *Registry) Store(ctx context.Context, name string) (*Store, error) {
+	entry, err := r.entry(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := entry.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStore(cli, entry.cfg.Database), nil
+}
+
+// State returns the connection state of the client registered under name.
+func (r *Registry) State(name string) ConnState {
+	r.mu.Lock()
+	entry, ok := r.entries[name]
+	r.mu.Unlock()
+
+	if !ok {
+		return StateIdle
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	return entry.state
+}
+
+func (r *Registry) entry(name string) (*registryEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.entries[name]; ok {
+		return entry, nil
+	}
+
+	if name != DefaultClientName {
+		return nil, fmt.Errorf("%w: %s", ErrClientNotRegistered, name)
+	}
+
+	cfg, err := LoadClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := newRegistryEntry(cfg)
+	r.entries[name] = entry
+
+	return entry, nil
+}
+
+// Names returns the registered names sorted alphabetically.
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Close disconnects every client. Configs stay registered, so the next call to Client
+// connects again.
+func (r *Registry) Close(ctx context.Context) error {
+	r.mu.Lock()
+	old := r.entries
+	r.entries = make(map[string]*registryEntry, len(old))
+	for name, entry := range old {
+		r.entries[name] = newRegistryEntry(entry.cfg)
+	}
+	r.mu.Unlock()
+
+	var errs []string
+	for name, entry := range old {
+		if err := entry.close(ctx); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	if len(errs) != 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("closing clients: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// acquire marks the start of a query, failing with ErrShutdown while draining.
+func (r *Registry) acquire() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.draining {
+		return ErrShutdown
+	}
+
+	r.inflight++
+	return nil
+}
+
+// release marks the end of a query started with acquire.
+func (r *Registry) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.inflight--
+	if r.inflight == 0 && r.drained != nil {
+		close(r.drained)
+		r.drained = nil
+	}
+}
+
+// Shutdown rejects new queries with ErrShutdown, waits for the running ones to finish or
+// for ctx to be done, and closes every client. Once it returns the registry accepts queries
+// again, connecting from scratch.
+func (r *Registry) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if r.draining {
+		r.mu.Unlock()
+		return ErrShutdown
+	}
+
+	r.draining = true
+	var wait chan struct{}
+	if r.inflight > 0 {
+		r.drained = make(chan struct{})
+		wait = r.drained
+	}
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.draining = false
+		r.drained = nil
+		r.mu.Unlock()
+	}()
+
+	var drainErr error
+	if wait != nil {
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			drainErr = fmt.Errorf("waiting for in-flight queries: %w", ctx.Err())
+		}
+	}
+
+	if err := r.Close(ctx); err != nil {
+		return err
+	}
+
+	return drainErr
+}
+
+// registryEntry holds a client and its state. A failed connection is never cached, the
+// next caller tries again.
+type registryEntry struct {
+	cfg ClientConfig
+
+	mu         sync.Mutex
+	client     *mongo.Client
+	state      ConnState
+	connecting bool
+	closed     bool
+	// changed is closed and replaced every time the state changes.
+	changed chan struct{}
+
+	stopHealth context.CancelFunc
+	healthDone chan struct{}
+}
+
+func newRegistryEntry(cfg ClientConfig) *registryEntry {
+	return &registryEntry{cfg: cfg, changed: make(chan struct{})}
+}
+
+func (e *registryEntry) get(ctx context.Context) (*mongo.Client, error) {
+	for {
+		e.mu.Lock()
+
+		switch {
+		case e.closed:
+			e.mu.Unlock()
+			return nil, ErrClientClosed
+		case e.client != nil && e.state == StateConnected:
+			cli := e.client
+			e.mu.Unlock()
+			return cli, nil
+		case e.client != nil && e.cfg.FailFast:
+			e.mu.Unlock()
+			return nil, ErrServerUnavailable
+		case e.client == nil && !e.connecting:
+			e.connecting = true
+			e.setState(StateConnecting)
+			e.mu.Unlock()
+
+			return e.connect(ctx)
+		}
+
+		wait := e.changed
+		e.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (e *registryEntry) connect(ctx context.Context) (*mongo.Client, error) {
+	var (
+		backoff = e.cfg.ConnectRetry.orDefault()
+		cli     *mongo.Client
+		err     error
+	)
+
+	for attempt := 0; ; attempt++ {
+		if cli, err = NewClient(ctx, e.cfg); err == nil || !backoff.Retry(attempt) {
+			break
+		}
+
+		if backoff.Wait(ctx, attempt) != nil {
+			break
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.connecting = false
+
+	if err != nil {
+		e.setState(StateIdle)
+		return nil, err
+	}
+
+	if e.closed {
+		_ = cli.Disconnect(context.Background())
+		return nil, ErrClientClosed
+	}
+
+	e.client = cli
+	e.setState(StateConnected)
+
+	if e.cfg.HealthCheckInterval > 0 {
+		hctx, cancel := context.WithCancel(context.Background())
+		e.stopHealth = cancel
+		e.healthDone = make(chan struct{})
+		go e.healthCheck(hctx, cli)
+	}
+
+	return cli, nil
+}
+
+// setState has to be called holding e.mu.
+func (e *registryEntry) setState(state ConnState) {
+	if e.state == state {
+		return
+	}
+
+	e.state = state
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+func (e *registryEntry) close(ctx context.Context) error {
+	e.mu.Lock()
+	e.closed = true
+	cli, stop, done := e.client, e.stopHealth, e.healthDone
+	e.client = nil
+	e.setState(StateIdle)
+	e.mu.Unlock()
+
+	if stop != nil {
+		stop()
+		<-done
+	}
+
+	if cli == nil {
+		return nil
+	}
+
+	return cli.Disconnect(ctx)
+}
+
+// RegisterClient registers a named client in the package registry.
+func RegisterClient(name string, cfg ClientConfig) error {
+	return defaultRegistry.Register(name, cfg)
+}
+
+// RegisteredClients returns the names registered in the package registry.
+func RegisteredClients() []string {
+	return defaultRegistry.Names()
+}
+
+// DefaultStore returns the store of the default client of the package registry.
+func DefaultStore(ctx context.Context) (*Store, error) {
+	return defaultRegistry.Store(ctx, DefaultClientName)
+}
+
+// ConnectionState returns the state of the client registered under name in the package registry.
+func ConnectionState(name string) ConnState {
+	return defaultRegistry.State(name)
+}
+
+// Shutdown gracefully stops the package registry, see Registry.Shutdown.
+func Shutdown(ctx context.Context) error {
+	return defaultRegistry.Shutdown(ctx)
+}
+
+// CloseClients disconnects every client of the package registry.
+func CloseClients(ctx context.Context) error {
+	return defaultRegistry.Close(ctx)
+}
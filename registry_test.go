@@ -0,0 +1,97 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	assert.NoError(t, r.Register("operational", ClientConfig{URI: "mongodb://operational:27017"}))
+	assert.NoError(t, r.Register("analytics", ClientConfig{URI: "mongodb://analytics:27017"}))
+
+	err := r.Register("analytics", ClientConfig{})
+	assert.True(t, errors.Is(err, ErrClientAlreadyRegistered))
+
+	assert.Equal(t, []string{"analytics", "operational"}, r.Names())
+
+	_, err = r.Client(context.TODO(), "reporting")
+	assert.True(t, errors.Is(err, ErrClientNotRegistered))
+
+	assert.NoError(t, r.Close(context.TODO()), "closing clients never dialed is a no-op")
+	assert.Equal(t, []string{"analytics", "operational"}, r.Names(), "configs survive a close")
+}
+
+func TestDialConnectionToUnknownClient(t *testing.T) {
+	_, err := DialConnectionTo(context.TODO(), "unknown", DoDeleteByObjectID[int](crudTestDb, crudTestCollection, 1))
+	assert.True(t, errors.Is(err, ErrClientNotRegistered))
+}
+
+func TestRegistryDoesNotCacheConnectionErrors(t *testing.T) {
+	r := NewRegistry()
+	cfg := ClientConfig{
+		URI:                    "mongodb://127.0.0.1:1",
+		ConnectTimeout:         50 * time.Millisecond,
+		ServerSelectionTimeout: 50 * time.Millisecond,
+		ConnectRetry:           Backoff{Initial: time.Millisecond, MaxAttempts: 2},
+	}
+	if err := r.Register("down", cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := r.Client(context.TODO(), "down")
+		assert.Error(t, err)
+		assert.Equal(t, StateIdle, r.State("down"), "a failed attempt leaves the client ready to try again")
+	}
+}
+
+func TestRegistryShutdownDrainsQueries(t *testing.T) {
+	r := NewRegistry()
+
+	if err := r.acquire(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() { done <- r.Shutdown(context.TODO()) }()
+
+	assert.Eventually(t, func() bool {
+		err := r.acquire()
+		if err == nil {
+			r.release()
+		}
+		return errors.Is(err, ErrShutdown)
+	}, time.Second, time.Millisecond, "new queries are rejected while draining")
+
+	select {
+	case <-done:
+		t.Fatal("shutdown returned with a query in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	r.release()
+	assert.NoError(t, <-done)
+
+	assert.NoError(t, r.acquire(), "queries are accepted again after the shutdown")
+	r.release()
+}
+
+func TestRegistryShutdownDeadline(t *testing.T) {
+	r := NewRegistry()
+
+	if err := r.acquire(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.release()
+
+	ctx, cl := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cl()
+
+	assert.ErrorIs(t, r.Shutdown(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,267 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository gives typed access to the documents T of a collection, identified by ID.
+//
+// ID is mapped to _id: a string is parsed as the hex of an ObjectID, a [16]byte like
+// uuid.UUID is stored as a UUID binary, and anything else, like primitive.ObjectID or an int,
+// is used as it is.
+type Repository[T any, ID any] struct {
+	database   string
+	collection string
+	dial       func(context.Context, QueryFunc) error
+}
+
+// NewRepository returns a repository of col running its queries with DialConnection.
+func NewRepository[T any, ID any](db, col string) *Repository[T, ID] {
+	return &Repository[T, ID]{
+		database:   db,
+		collection: col,
+		dial: func(ctx context.Context, query QueryFunc) error {
+			_, err := DialConnection(ctx, func(ctx context.Context, c *mongo.Client) (*struct{}, error) {
+				return nil, query(ctx, c)
+			})
+			return err
+		},
+	}
+}
+
+// NewStoreRepository returns a repository of col in the default database of s.
+func NewStoreRepository[T any, ID any](s *Store, col string) *Repository[T, ID] {
+	return &Repository[T, ID]{
+		database:   s.Database(),
+		collection: col,
+		dial: func(ctx context.Context, query QueryFunc) error {
+			return query(ctx, s.Client())
+		},
+	}
+}
+
+// Database returns the database of the repository.
+func (r *Repository[T, ID]) Database() string {
+	return r.database
+}
+
+// Collection returns the collection of the repository.
+func (r *Repository[T, ID]) Collection() string {
+	return r.collection
+}
+
+// Create inserts doc and returns its id.
+func (r *Repository[T, ID]) Create(ctx context.Context, doc T) (ID, error) {
+	ids, err := r.CreateMany(ctx, []T{doc})
+	if err != nil {
+		var zero ID
+		return zero, err
+	}
+
+	return ids[0], nil
+}
+
+// CreateMany inserts docs and returns their ids, in the same order.
+func (r *Repository[T, ID]) CreateMany(ctx context.Context, docs []T) ([]ID, error) {
+	imr, err := repositoryRun(ctx, r, DoInsert(r.database, r.collection, docs))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]ID, len(imr.InsertedIDs))
+	for i, value := range imr.InsertedIDs {
+		if ids[i], err = fromIDValue[ID](value); err != nil {
+			return nil, err
+		}
+	}
+
+	return ids, nil
+}
+
+// Get returns the document with id, or ErrNotFound.
+func (r *Repository[T, ID]) Get(ctx context.Context, id ID) (*T, error) {
+	filter, err := idFilter(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return repositoryRun(ctx, r, DoFindOne[T](r.database, r.collection, filter))
+}
+
+// GetMany returns the documents with ids. Ids not found are skipped, so the result may be
+// shorter and is not in the order of ids.
+func (r *Repository[T, ID]) GetMany(ctx context.Context, ids ...ID) ([]T, error) {
+	values := make(bson.A, len(ids))
+	for i, id := range ids {
+		value, err := toIDValue(id)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return r.Find(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: values}}}})
+}
+
+// Find returns the documents matching filter.
+func (r *Repository[T, ID]) Find(ctx context.Context, filter any, opts ...FindOption) ([]T, error) {
+	docs, err := repositoryRun(ctx, r, DoFindAll[T](r.database, r.collection, orEmptyFilter(filter), opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	return *docs, nil
+}
+
+// FindOne returns the first document matching filter, or ErrNotFound.
+func (r *Repository[T, ID]) FindOne(ctx context.Context, filter any, opts ...FindOption) (*T, error) {
+	return repositoryRun(ctx, r, DoFindOne[T](r.database, r.collection, orEmptyFilter(filter), opts...))
+}
+
+// Update applies update, made of update operators, to the document with id. It returns
+// ErrNotFound when there is no such document, unless WithUpsert created it. The version and
+// updated timestamp of T, if any, are updated too, and with WithVersion a *ConflictError is
+// returned when the document is at another version.
+func (r *Repository[T, ID]) Update(ctx context.Context, id ID, update any, opts ...UpdateOption) error {
+	filter, err := idFilter(id)
+	if err != nil {
+		return err
+	}
+
+	ur, err := repositoryRun(ctx, r, DoUpdateOneOf[T](r.database, r.collection, filter, update, opts...))
+	if err != nil {
+		return err
+	}
+
+	if ur.MatchedCount == 0 && ur.UpsertedCount == 0 {
+		return &kindError{kind: ErrNotFound, err: fmt.Errorf("no document with _id %v", id)}
+	}
+
+	return nil
+}
+
+// Delete deletes the document with id, or returns ErrNotFound.
+func (r *Repository[T, ID]) Delete(ctx context.Context, id ID) error {
+	filter, err := idFilter(id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := repositoryRun(ctx, r, DoDeleteOne(r.database, r.collection, filter))
+	if err != nil {
+		return err
+	}
+
+	if *deleted == 0 {
+		return &kindError{kind: ErrNotFound, err: fmt.Errorf("no document with _id %v", id)}
+	}
+
+	return nil
+}
+
+// Exists reports whether any document matches filter.
+func (r *Repository[T, ID]) Exists(ctx context.Context, filter any) (bool, error) {
+	count, err := repositoryRun(ctx, r, DoCount(r.database, r.collection, filter, options.Count().SetLimit(1)))
+	if err != nil {
+		return false, err
+	}
+
+	return *count > 0, nil
+}
+
+// Count returns the number of documents matching filter.
+func (r *Repository[T, ID]) Count(ctx context.Context, filter any) (int64, error) {
+	count, err := repositoryRun(ctx, r, DoCount(r.database, r.collection, filter))
+	if err != nil {
+		return 0, err
+	}
+
+	return *count, nil
+}
+
+// repositoryRun runs query with the client of r and returns its result.
+func repositoryRun[T, ID, R any](ctx context.Context, r *Repository[T, ID], query func(context.Context, *mongo.Client) (*R, error)) (*R, error) {
+	var result *R
+
+	err := r.dial(ctx, func(ctx context.Context, c *mongo.Client) (err error) {
+		result, err = query(ctx, c)
+		return err
+	})
+
+	return result, err
+}
+
+func idFilter[ID any](id ID) (bson.D, error) {
+	value, err := toIDValue(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.D{{Key: "_id", Value: value}}, nil
+}
+
+// toIDValue returns the _id stored for id.
+func toIDValue[ID any](id ID) (any, error) {
+	switch v := any(id).(type) {
+	case string:
+		oid, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return nil, &InvalidObjectIDError{Input: v, Err: err}
+		}
+		return oid, nil
+	case primitive.ObjectID:
+		return v, nil
+	}
+
+	if rv := reflect.ValueOf(id); isUUIDType(rv.Type()) {
+		data := make([]byte, 16)
+		reflect.Copy(reflect.ValueOf(data), rv)
+		return primitive.Binary{Subtype: bsontype.BinaryUUID, Data: data}, nil
+	}
+
+	return id, nil
+}
+
+// fromIDValue is the inverse of toIDValue, turning an _id into an ID.
+func fromIDValue[ID any](value any) (ID, error) {
+	var id ID
+
+	switch target := any(&id).(type) {
+	case *string:
+		if oid, ok := value.(primitive.ObjectID); ok {
+			*target = oid.Hex()
+			return id, nil
+		}
+	case *primitive.ObjectID:
+		if oid, ok := value.(primitive.ObjectID); ok {
+			*target = oid
+			return id, nil
+		}
+	}
+
+	if rv := reflect.ValueOf(&id).Elem(); isUUIDType(rv.Type()) {
+		if bin, ok := value.(primitive.Binary); ok && len(bin.Data) == 16 {
+			reflect.Copy(rv, reflect.ValueOf(bin.Data))
+			return id, nil
+		}
+	}
+
+	ids, err := decodeValues[ID]([]any{value})
+	if err != nil {
+		return id, fmt.Errorf("_id %v: %w", value, err)
+	}
+
+	return ids[0], nil
+}
+
+func isUUIDType(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
@@ -0,0 +1,68 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type testUUID [16]byte
+
+func TestIDMapping(t *testing.T) {
+	oid := primitive.NewObjectID()
+
+	t.Run("hex strings are object ids", func(t *testing.T) {
+		value, err := toIDValue(oid.Hex())
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, oid, value)
+
+		id, err := fromIDValue[string](oid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, oid.Hex(), id)
+
+		_, err = toIDValue("not hex")
+		assert.ErrorIs(t, err, ErrInvalidObjectID)
+	})
+
+	t.Run("uuids are binaries", func(t *testing.T) {
+		uuid := testUUID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+		value, err := toIDValue(uuid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, primitive.Binary{Subtype: bsontype.BinaryUUID, Data: uuid[:]}, value)
+
+		id, err := fromIDValue[testUUID](value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, uuid, id)
+	})
+
+	t.Run("other ids are used as they are", func(t *testing.T) {
+		value, err := toIDValue(oid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, oid, value)
+
+		value, err = toIDValue(42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 42, value)
+
+		id, err := fromIDValue[int](int32(42))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 42, id)
+	})
+}
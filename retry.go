@@ -0,0 +1,162 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// ErrorClass tells whether a failed query can be executed again.
+type ErrorClass int
+
+const (
+	// ClassPermanent errors fail again if retried, like a duplicate key or a validation error.
+	ClassPermanent ErrorClass = iota
+	// ClassRetryable errors guarantee the operation was not applied, so any query can be retried.
+	ClassRetryable
+	// ClassRetryableIfIdempotent errors may happen after the server applied the operation, like
+	// a network error while reading the reply, so only idempotent queries are retried.
+	ClassRetryableIfIdempotent
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ClassPermanent:
+		return "permanent"
+	case ClassRetryable:
+		return "retryable"
+	case ClassRetryableIfIdempotent:
+		return "retryable if idempotent"
+	}
+	return "unknown"
+}
+
+// Server error codes, see https://github.com/mongodb/mongo/blob/master/src/mongo/base/error_codes.yml
+const (
+	codeHostUnreachable                 = 6
+	codeHostNotFound                    = 7
+	codeNetworkTimeout                  = 89
+	codeShutdownInProgress              = 91
+	codePrimarySteppedDown              = 189
+	codeExceededTimeLimit               = 262
+	codeDuplicateKey                    = 11000
+	codeDuplicateKeyLegacy              = 11001
+	codeDuplicateKeyUpdate              = 12582
+	codeInterruptedAtShutdown           = 11600
+	codeInterruptedDueToReplStateChange = 11602
+	codeSocketException                 = 9001
+	codeNotWritablePrimary              = 10107
+	codeNotPrimaryNoSecondaryOk         = 13435
+	codeNotPrimaryOrSecondary           = 13436
+	codeDocumentValidationFailure       = 121
+)
+
+// rejectedCodes are returned by servers which refused the operation before running it.
+var rejectedCodes = []int{codeNotWritablePrimary, codeNotPrimaryNoSecondaryOk, codeNotPrimaryOrSecondary}
+
+// interruptedCodes may be returned after the operation was partially or fully applied.
+var interruptedCodes = []int{
+	codeHostUnreachable, codeHostNotFound, codeNetworkTimeout, codeShutdownInProgress, codePrimarySteppedDown,
+	codeExceededTimeLimit, codeInterruptedAtShutdown, codeInterruptedDueToReplStateChange, codeSocketException,
+}
+
+var duplicateKeyCodes = []int{codeDuplicateKey, codeDuplicateKeyLegacy, codeDuplicateKeyUpdate}
+
+// Classify tells whether err is worth retrying.
+func Classify(err error) ErrorClass {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, mongo.ErrClientDisconnected) {
+		return ClassPermanent
+	}
+
+	var delivered *deliveredError
+	if errors.As(err, &delivered) {
+		return ClassPermanent
+	}
+
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		switch {
+		case hasAnyCode(serverErr, duplicateKeyCodes), serverErr.HasErrorCode(codeDocumentValidationFailure):
+			return ClassPermanent
+		case hasAnyCode(serverErr, rejectedCodes):
+			return ClassRetryable
+		// The driver already retried the write labeled RetryableWriteError once with the same
+		// transaction number, executing it again here would be a new write.
+		case serverErr.HasErrorLabel("RetryableWriteError"), serverErr.HasErrorLabel("NetworkError"), hasAnyCode(serverErr, interruptedCodes):
+			return ClassRetryableIfIdempotent
+		}
+	}
+
+	var selectionErr topology.ServerSelectionError
+	if errors.As(err, &selectionErr) {
+		// No server was selected, so nothing was sent.
+		return ClassRetryable
+	}
+
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return ClassRetryableIfIdempotent
+	}
+
+	return ClassPermanent
+}
+
+func hasAnyCode(err mongo.ServerError, codes []int) bool {
+	for _, code := range codes {
+		if err.HasErrorCode(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShouldRetry reports whether a query failing with err can be executed again.
+func ShouldRetry(err error, idempotent bool) bool {
+	switch Classify(err) {
+	case ClassRetryable:
+		return true
+	case ClassRetryableIfIdempotent:
+		return idempotent
+	}
+	return false
+}
+
+// RetryPolicy configures the Retry middleware.
+type RetryPolicy struct {
+	// Backoff between attempts, DefaultBackoff if it is the zero value.
+	Backoff Backoff
+	// RetryNonIdempotent retries every query on ClassRetryableIfIdempotent errors, even
+	// inserts, accepting the risk of applying them twice.
+	RetryNonIdempotent bool
+}
+
+// Retry returns a middleware executing again the queries which failed with a transient error.
+// Non idempotent operations, see Operation.Idempotent, are only retried when the server
+// guarantees the first attempt was not applied.
+func Retry(policy RetryPolicy) Middleware {
+	backoff := policy.Backoff.orDefault()
+
+	return func(next QueryFunc) QueryFunc {
+		return func(ctx context.Context, c *mongo.Client) error {
+			// Single statements are not retried inside a transaction, the whole transaction is.
+			if inTransaction(ctx) {
+				return next(ctx, c)
+			}
+
+			op, _ := OperationFromContext(ctx)
+			idempotent := op.Idempotent || policy.RetryNonIdempotent
+
+			for attempt := 0; ; attempt++ {
+				err := next(ctx, c)
+				if err == nil || ctx.Err() != nil || !backoff.Retry(attempt) || !ShouldRetry(err, idempotent) {
+					return err
+				}
+
+				if backoff.Wait(ctx, attempt) != nil {
+					return err
+				}
+			}
+		}
+	}
+}
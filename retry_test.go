@@ -0,0 +1,145 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+func TestClassify(t *testing.T) {
+	var testCases = []struct {
+		description string
+		err         error
+		want        ErrorClass
+	}{
+		{
+			description: "duplicate key is permanent",
+			err:         mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: codeDuplicateKey}}},
+			want:        ClassPermanent,
+		},
+		{
+			description: "document validation is permanent",
+			err:         mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: codeDocumentValidationFailure}}},
+			want:        ClassPermanent,
+		},
+		{
+			description: "retryable write label is retryable only if idempotent",
+			err:         mongo.CommandError{Code: codeInterruptedDueToReplStateChange, Labels: []string{"RetryableWriteError"}},
+			want:        ClassRetryableIfIdempotent,
+		},
+		{
+			description: "shutdown in progress is retryable only if idempotent",
+			err:         mongo.CommandError{Code: codeShutdownInProgress},
+			want:        ClassRetryableIfIdempotent,
+		},
+		{
+			description: "not primary is retryable",
+			err:         mongo.CommandError{Code: codeNotWritablePrimary},
+			want:        ClassRetryable,
+		},
+		{
+			description: "wrapped not primary is retryable",
+			err:         fmt.Errorf("inserting: %w", mongo.CommandError{Code: codeNotPrimaryNoSecondaryOk}),
+			want:        ClassRetryable,
+		},
+		{
+			description: "server selection error is retryable",
+			err:         topology.ServerSelectionError{Wrapped: topology.ErrServerSelectionTimeout},
+			want:        ClassRetryable,
+		},
+		{
+			description: "network error is retryable only if idempotent",
+			err:         mongo.CommandError{Labels: []string{"NetworkError"}},
+			want:        ClassRetryableIfIdempotent,
+		},
+		{
+			description: "primary stepdown is retryable only if idempotent",
+			err:         mongo.CommandError{Code: codePrimarySteppedDown},
+			want:        ClassRetryableIfIdempotent,
+		},
+		{
+			description: "deadline exceeded is retryable only if idempotent",
+			err:         context.DeadlineExceeded,
+			want:        ClassRetryableIfIdempotent,
+		},
+		{
+			description: "cancellation is permanent",
+			err:         context.Canceled,
+			want:        ClassPermanent,
+		},
+		{
+			description: "unknown errors are permanent",
+			err:         errors.New("boom"),
+			want:        ClassPermanent,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.description, func(t *testing.T) {
+			assert.Equal(t, tCase.want, Classify(tCase.err))
+		})
+	}
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	t.Cleanup(ClearMiddlewares)
+	Use(Retry(RetryPolicy{Backoff: Backoff{Initial: time.Millisecond, MaxAttempts: 3}}))
+
+	networkErr := mongo.CommandError{Labels: []string{"NetworkError"}}
+
+	var testCases = []struct {
+		description string
+		idempotent  bool
+		err         error
+		wantCalls   int
+	}{
+		{
+			description: "idempotent operations are retried on network errors",
+			idempotent:  true,
+			err:         networkErr,
+			wantCalls:   3,
+		},
+		{
+			description: "non idempotent operations are not retried on network errors",
+			err:         networkErr,
+			wantCalls:   1,
+		},
+		{
+			description: "non idempotent operations are not retried on retryable write errors",
+			err:         mongo.CommandError{Labels: []string{"NetworkError", "RetryableWriteError"}},
+			wantCalls:   1,
+		},
+		{
+			description: "non idempotent operations are retried when the server rejected them",
+			err:         mongo.CommandError{Code: codeNotWritablePrimary},
+			wantCalls:   3,
+		},
+		{
+			description: "permanent errors are never retried",
+			idempotent:  true,
+			err:         mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: codeDuplicateKey}}},
+			wantCalls:   1,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.description, func(t *testing.T) {
+			calls := 0
+			query := Instrument(Operation{Name: "test", Idempotent: tCase.idempotent}, func(ctx context.Context, c *mongo.Client) (*int, error) {
+				calls++
+				return nil, tCase.err
+			})
+
+			_, err := query(context.TODO(), nil)
+
+			assert.Error(t, err)
+			assert.Equal(t, tCase.wantCalls, calls)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package mongodb
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Fields set on the documents deleted from a collection with soft delete enabled.
+const (
+	DeletedAtField = "deleted_at"
+	DeletedByField = "deleted_by"
+)
+
+type visibility int
+
+const (
+	visibleOnly visibility = iota
+	visibleWithDeleted
+	visibleOnlyDeleted
+)
+
+type (
+	visibilityKey struct{}
+	actorKey      struct{}
+)
+
+var (
+	softDeleted    = map[string]struct{}{}
+	softDeletedMtx sync.RWMutex
+)
+
+// EnableSoftDelete turns the deletes of col into updates setting DeletedAtField and
+// DeletedByField. The find and count helpers then skip the documents deleted, unless the
+// context comes from WithDeleted or OnlyDeleted. DoEstimatedCount is not filtered, it reads
+// the collection metadata.
+func EnableSoftDelete(db, col string) {
+	softDeletedMtx.Lock()
+	defer softDeletedMtx.Unlock()
+
+	softDeleted[db+"."+col] = struct{}{}
+}
+
+// DisableSoftDelete goes back to removing the documents of col. Documents already soft
+// deleted are kept and become visible again.
+func DisableSoftDelete(db, col string) {
+	softDeletedMtx.Lock()
+	defer softDeletedMtx.Unlock()
+
+	delete(softDeleted, db+"."+col)
+}
+
+func softDeleteEnabled(db, col string) bool {
+	softDeletedMtx.RLock()
+	defer softDeletedMtx.RUnlock()
+
+	_, ok := softDeleted[db+"."+col]
+	return ok
+}
+
+// WithDeleted returns a context whose queries include the soft deleted documents.
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, visibilityKey{}, visibleWithDeleted)
+}
+
+// OnlyDeleted returns a context whose queries only match the soft deleted documents.
+func OnlyDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, visibilityKey{}, visibleOnlyDeleted)
+}
+
+// WithActor returns a context whose soft deletes are recorded as done by actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set with WithActor.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok
+}
+
+// visibleFilter restricts filter to the documents visible from ctx.
+func visibleFilter(ctx context.Context, db, col string, filter any) any {
+	if !softDeleteEnabled(db, col) {
+		return filter
+	}
+
+	mode, _ := ctx.Value(visibilityKey{}).(visibility)
+	switch mode {
+	case visibleWithDeleted:
+		return filter
+	case visibleOnlyDeleted:
+		return andAny(filter, bson.D{{Key: DeletedAtField, Value: bson.D{{Key: "$ne", Value: nil}}}})
+	default:
+		return andAny(filter, bson.D{{Key: DeletedAtField, Value: nil}})
+	}
+}
+
+// andAny returns a filter matching both filter, of any type, and cond.
+func andAny(filter any, cond bson.D) any {
+	if empty, err := isEmptyFilter(filter); err == nil && empty {
+		return cond
+	}
+
+	return bson.D{{Key: "$and", Value: bson.A{filter, cond}}}
+}
+
+// notDeleted restricts filter to the documents not soft deleted.
+func notDeleted(filter any) any {
+	return andAny(filter, bson.D{{Key: DeletedAtField, Value: nil}})
+}
+
+// softDeleteUpdate is the update marking documents as deleted by the actor of ctx.
+func softDeleteUpdate(ctx context.Context) bson.D {
+	set := bson.D{{Key: DeletedAtField, Value: now()}}
+	if actor, ok := ActorFromContext(ctx); ok {
+		set = append(set, bson.E{Key: DeletedByField, Value: actor})
+	}
+
+	return bson.D{{Key: "$set", Value: set}}
+}
+
+// softDelete marks the documents matching filter as deleted instead of removing them.
+func softDelete(ctx context.Context, coll *mongo.Collection, filter any, many bool) (*int64, error) {
+	var (
+		query  = notDeleted(filter)
+		update = softDeleteUpdate(ctx)
+		ur     *mongo.UpdateResult
+		err    error
+	)
+
+	if many {
+		ur, err = coll.UpdateMany(ctx, query, update)
+	} else {
+		ur, err = coll.UpdateOne(ctx, query, update)
+	}
+	if ur == nil {
+		return nil, translateError(err)
+	}
+
+	return &ur.ModifiedCount, translateError(err)
+}
+
+// DoRestore brings back the soft deleted documents matching filter and returns how many were
+// restored. Like deletes, it returns ErrEmptyFilter for a nil or empty filter unless
+// WithAllowFullCollection is passed; the other options are ignored.
+func DoRestore(db, col string, filter any, opts ...DeleteOption) CountFunc {
+	return Instrument(Operation{Name: "restore", Database: db, Collection: col, Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*int64, error) {
+		filter, err := guardEmptyFilter(filter, newDeleteSettings(opts))
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			query  = andAny(filter, bson.D{{Key: DeletedAtField, Value: bson.D{{Key: "$ne", Value: nil}}}})
+			update = bson.D{{Key: "$unset", Value: bson.D{{Key: DeletedAtField, Value: ""}, {Key: DeletedByField, Value: ""}}}}
+		)
+
+		ur, err := c.Database(db).Collection(col).UpdateMany(ctx, query, update)
+		if ur == nil {
+			return nil, translateError(err)
+		}
+
+		return &ur.ModifiedCount, translateError(err)
+	})
+}
@@ -0,0 +1,78 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	o "github.com/MrTimeout/go-mongo/operator"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestVisibleFilter(t *testing.T) {
+	const db, col = "testing", "soft_delete_unit"
+
+	var (
+		ctx         = context.Background()
+		filter      = o.F("name", o.Eq("x"))
+		notDeleted  = bson.D{{Key: DeletedAtField, Value: nil}}
+		onlyDeleted = bson.D{{Key: DeletedAtField, Value: bson.D{{Key: "$ne", Value: nil}}}}
+	)
+
+	assert.Equal(t, filter, visibleFilter(ctx, db, col, filter), "collections without soft delete are not filtered")
+
+	EnableSoftDelete(db, col)
+	t.Cleanup(func() { DisableSoftDelete(db, col) })
+
+	assert.Equal(t, bson.D{{Key: "$and", Value: bson.A{filter, notDeleted}}}, visibleFilter(ctx, db, col, filter))
+	assert.Equal(t, notDeleted, visibleFilter(ctx, db, col, nil))
+	assert.Equal(t, notDeleted, visibleFilter(ctx, db, col, bson.M{}))
+	assert.Equal(t, filter, visibleFilter(WithDeleted(ctx), db, col, filter))
+	assert.Equal(t, bson.D{{Key: "$and", Value: bson.A{filter, onlyDeleted}}}, visibleFilter(OnlyDeleted(ctx), db, col, filter))
+}
+
+func TestActorFromContext(t *testing.T) {
+	_, ok := ActorFromContext(context.Background())
+	assert.False(t, ok)
+
+	actor, ok := ActorFromContext(WithActor(context.Background(), "auditor"))
+	assert.True(t, ok)
+	assert.Equal(t, "auditor", actor)
+}
+
+func TestSoftDeleteBulkModels(t *testing.T) {
+	const db, col = "testing", "soft_delete_bulk_unit"
+
+	EnableSoftDelete(db, col)
+	t.Cleanup(func() { DisableSoftDelete(db, col) })
+
+	filter := o.F("name", o.Eq("x"))
+	writes, _, err := toWriteModels(WithActor(context.Background(), "auditor"), db, col, []WriteModel{
+		DeleteModel{Filter: filter, Many: true},
+		UpdateModel{Filter: filter, Update: bson.D{{Key: "$set", Value: filter}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notDeleted := bson.D{{Key: "$and", Value: bson.A{filter, bson.D{{Key: DeletedAtField, Value: nil}}}}}
+
+	if assert.IsType(t, &mongo.UpdateManyModel{}, writes[0], "deletes are turned into updates") {
+		model := writes[0].(*mongo.UpdateManyModel)
+		assert.Equal(t, notDeleted, model.Filter)
+		assert.Equal(t, "auditor", model.Update.(bson.D)[0].Value.(bson.D)[1].Value)
+	}
+
+	if assert.IsType(t, &mongo.UpdateOneModel{}, writes[1]) {
+		assert.Equal(t, notDeleted, writes[1].(*mongo.UpdateOneModel).Filter, "soft deleted documents are not updated")
+	}
+}
+
+func TestRestoreEmptyFilter(t *testing.T) {
+	_, err := DoRestore("testing", "col", nil)(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrEmptyFilter)
+
+	_, err = DoRestore("testing", "col", bson.M{})(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrEmptyFilter)
+}
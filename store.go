@@ -0,0 +1,89 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Store binds a client to a default database. Several stores can live in the same process,
+// each one with its own client or sharing one.
+type Store struct {
+	client   *mongo.Client
+	database string
+	// owned is true when the store created the client, so Close has to disconnect it.
+	owned bool
+}
+
+// NewStore returns a store using an existing client. Closing the store does not disconnect it.
+func NewStore(client *mongo.Client, database string) *Store {
+	return &Store{client: client, database: database}
+}
+
+// OpenStore connects a new client using cfg. The default database is cfg.Database.
+func OpenStore(ctx context.Context, cfg ClientConfig) (*Store, error) {
+	cli, err := NewClient(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{client: cli, database: cfg.Database, owned: true}, nil
+}
+
+// Client returns the client of the store.
+func (s *Store) Client() *mongo.Client {
+	return s.client
+}
+
+// Database returns the default database of the store.
+func (s *Store) Database() string {
+	return s.database
+}
+
+// Collection returns a handle to a collection of the default database.
+func (s *Store) Collection(col string) *mongo.Collection {
+	return s.client.Database(s.database).Collection(col)
+}
+
+// Close disconnects the client if the store opened it.
+func (s *Store) Close(ctx context.Context) error {
+	if !s.owned {
+		return nil
+	}
+
+	return s.client.Disconnect(ctx)
+}
+
+// Insert is DoInsert using the default database. Hooks with pointer receivers are called on
+// docs passed by value too.
+func (s *Store) Insert(ctx context.Context, col string, docs ...any) (*mongo.InsertManyResult, error) {
+	return DoInsert(s.database, col, docs)(ctx, s.client)
+}
+
+// Find is DoFind using the default database. result must be a pointer to a slice.
+func (s *Store) Find(ctx context.Context, col string, filter, result any, opts ...*options.FindOptions) error {
+	_, err := doFind(s.database, col, filter, result, opts...)(ctx, s.client)
+	return err
+}
+
+// FindAndUpdate is DoFindAndUpdate using the default database.
+func (s *Store) FindAndUpdate(ctx context.Context, col string, filter, toUpdate any) (*mongo.SingleResult, error) {
+	return DoFindAndUpdate(s.database, col, filter, toUpdate)(ctx, s.client)
+}
+
+// DeleteByObjectID is DoDeleteByObjectID using the default database.
+func (s *Store) DeleteByObjectID(ctx context.Context, col string, objectIDs ...any) (*int64, error) {
+	return DoDeleteByObjectID(s.database, col, objectIDs...)(ctx, s.client)
+}
+
+// DeleteByStringObjectID is DoDeleteByStringObjectID using the default database.
+func (s *Store) DeleteByStringObjectID(ctx context.Context, col string, objectIDs ...string) (*int64, error) {
+	return DoDeleteByStringObjectID(s.database, col, objectIDs...)(ctx, s.client)
+}
+
+// Run executes any query func, like the ones returned by the Do* functions, using the
+// client of the store.
+func Run[T any](ctx context.Context, s *Store, query func(context.Context, *mongo.Client) (*T, error)) (*T, error) {
+	return query(ctx, s.client)
+}
@@ -0,0 +1,35 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestStoresAreIndependent(t *testing.T) {
+	first, err := mongo.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := mongo.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := []*Store{NewStore(first, "orders"), NewStore(second, "analytics")}
+
+	for i, want := range []*mongo.Client{first, second} {
+		got, err := Run(context.TODO(), stores[i], func(ctx context.Context, c *mongo.Client) (*mongo.Client, error) {
+			return c, nil
+		})
+
+		assert.NoError(t, err)
+		assert.Same(t, want, got)
+	}
+
+	assert.Equal(t, "orders", stores[0].Database())
+	assert.Equal(t, "analytics", stores[1].Collection("events").Database().Name())
+	assert.NoError(t, stores[0].Close(context.TODO()), "stores do not close clients they did not open")
+}
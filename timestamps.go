@@ -0,0 +1,156 @@
+package mongodb
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Options of the mongo tag marking the timestamps of a document, which can be a time.Time, a
+// *time.Time or a primitive.DateTime:
+//
+//	CreatedAt time.Time `bson:"created_at" mongo:"created"`
+//	UpdatedAt time.Time `bson:"updated_at" mongo:"updated"`
+//
+// Inserts fill both when they are zero. Replaces set the updated one, and the updates knowing
+// T, DoUpdateOneOf, DoUpdateManyOf, UpdateModelOf, DoFindOneAndUpdate and Repository.Update,
+// add a $set of it unless the update already changes it. DoUpdateOne, DoUpdateMany,
+// DoFindAndUpdate and UpdateModel send their update as it is.
+const (
+	createdTag = "created"
+	updatedTag = "updated"
+)
+
+var (
+	clock    = time.Now
+	clockMtx sync.RWMutex
+)
+
+// SetClock sets the function returning the time written in the timestamps, time.Now when nil.
+// Tests can use it to get deterministic documents.
+func SetClock(now func() time.Time) {
+	clockMtx.Lock()
+	defer clockMtx.Unlock()
+
+	if now == nil {
+		now = time.Now
+	}
+	clock = now
+}
+
+func now() time.Time {
+	clockMtx.RLock()
+	defer clockMtx.RUnlock()
+
+	return clock()
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	timePtrType  = reflect.TypeOf(&time.Time{})
+	dateTimeType = reflect.TypeOf(primitive.DateTime(0))
+)
+
+func isTimestamp(t reflect.Type) bool {
+	return t == timeType || t == timePtrType || t == dateTimeType
+}
+
+// stampInsert returns a copy of doc with its zero timestamps set to now.
+func stampInsert[T any](doc T) T {
+	return stamp(doc, true)
+}
+
+// stampReplace returns a copy of doc with its updated timestamp set to now.
+func stampReplace[T any](doc T) T {
+	return stamp(doc, false)
+}
+
+func stamp[T any](doc T, insert bool) T {
+	v := reflect.ValueOf(doc)
+	if !v.IsValid() {
+		return doc
+	}
+
+	created := taggedField(v.Type(), createdTag, isTimestamp)
+	updated := taggedField(v.Type(), updatedTag, isTimestamp)
+	if !insert {
+		created = nil
+	}
+	if created == nil && updated == nil {
+		return doc
+	}
+
+	// Work on a copy, so the document of the caller is not modified.
+	var target, result reflect.Value
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return doc
+		}
+		result = reflect.New(v.Type().Elem())
+		result.Elem().Set(v.Elem())
+		target = result.Elem()
+	} else {
+		result = reflect.New(v.Type()).Elem()
+		result.Set(v)
+		target = result
+	}
+
+	t := now()
+	if created != nil {
+		setTimestamp(target.FieldByIndex(created.index), t, true)
+	}
+	if updated != nil {
+		setTimestamp(target.FieldByIndex(updated.index), t, insert)
+	}
+
+	return result.Interface().(T)
+}
+
+func setTimestamp(field reflect.Value, t time.Time, onlyZero bool) {
+	if onlyZero && !field.IsZero() {
+		return
+	}
+
+	switch field.Type() {
+	case timeType:
+		field.Set(reflect.ValueOf(t))
+	case timePtrType:
+		field.Set(reflect.ValueOf(&t))
+	case dateTimeType:
+		field.Set(reflect.ValueOf(primitive.NewDateTimeFromTime(t)))
+	}
+}
+
+// prepareUpdate adds to update the changes every update of a T makes: the increment of its
+// version and the $set of its updated timestamp.
+func prepareUpdate[T any](update any) (any, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	if vf, ok := versionFieldOf[T](); ok {
+		var err error
+		if update, err = vf.increment(update); err != nil {
+			return nil, err
+		}
+	}
+
+	updated := taggedField(t, updatedTag, isTimestamp)
+	if updated == nil {
+		return update, nil
+	}
+
+	set := bson.E{Key: updated.name, Value: now()}
+
+	if isPipeline(update) {
+		return appendStage(update, bson.D{{Key: "$set", Value: bson.D{set}}}), nil
+	}
+
+	doc, err := updateDocument(update)
+	if err != nil {
+		return nil, err
+	}
+
+	return withOperator(doc, "$set", set)
+}
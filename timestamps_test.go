@@ -0,0 +1,86 @@
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type stamped struct {
+	Name      string             `bson:"name"`
+	CreatedAt time.Time          `bson:"created_at" mongo:"created"`
+	UpdatedAt primitive.DateTime `bson:"updated_at" mongo:"updated"`
+}
+
+func fixClock(t *testing.T, at time.Time) {
+	SetClock(func() time.Time { return at })
+	t.Cleanup(func() { SetClock(nil) })
+}
+
+func TestStampInsert(t *testing.T) {
+	at := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	fixClock(t, at)
+
+	doc := stamped{Name: "x"}
+	got := stampInsert(doc)
+
+	assert.Equal(t, stamped{Name: "x", CreatedAt: at, UpdatedAt: primitive.NewDateTimeFromTime(at)}, got)
+	assert.True(t, doc.CreatedAt.IsZero(), "the caller's document is not modified")
+
+	earlier := at.Add(-time.Hour)
+	assert.Equal(t, earlier, stampInsert(&stamped{CreatedAt: earlier}).CreatedAt, "timestamps already set are kept")
+
+	var anyDoc any = stamped{}
+	assert.Equal(t, at, stampInsert(anyDoc).(stamped).CreatedAt, "documents passed as any are stamped too")
+
+	assert.Equal(t, bson.D{{Key: "a", Value: 1}}, stampInsert(bson.D{{Key: "a", Value: 1}}))
+}
+
+func TestStampReplace(t *testing.T) {
+	at := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	fixClock(t, at)
+
+	created := at.Add(-time.Hour)
+	got := stampReplace(stamped{CreatedAt: created, UpdatedAt: primitive.NewDateTimeFromTime(created)})
+
+	assert.Equal(t, stamped{CreatedAt: created, UpdatedAt: primitive.NewDateTimeFromTime(at)}, got)
+}
+
+func TestPrepareUpdate(t *testing.T) {
+	at := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	fixClock(t, at)
+
+	update, err := prepareUpdate[stamped](bson.D{{Key: "$set", Value: bson.D{{Key: "name", Value: "y"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bson.D{{Key: "$set", Value: bson.D{{Key: "name", Value: "y"}, {Key: "updated_at", Value: at}}}}, update)
+
+	update, err = prepareUpdate[stamped](mongo.Pipeline{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bson.A{bson.D{{Key: "$set", Value: bson.D{{Key: "updated_at", Value: at}}}}}, update)
+
+	update, err = prepareUpdate[Person](bson.M{"$set": bson.M{"age": 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bson.M{"$set": bson.M{"age": 3}}, update, "types without timestamps nor version are left alone")
+
+	update, err = prepareUpdate[stamped](bson.D{{Key: "$currentDate", Value: bson.D{{Key: "updated_at", Value: true}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bson.D{{Key: "$currentDate", Value: bson.D{{Key: "updated_at", Value: true}}}}, update, "updates already changing the timestamp are left alone")
+
+	update, err = prepareUpdate[versioned](bson.M{"$inc": bson.M{"_v": 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bson.D{{Key: "$inc", Value: bson.D{{Key: "_v", Value: int32(2)}}}}, update, "updates already changing the version are left alone")
+}
@@ -0,0 +1,135 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// DefaultTransactionTimeout is the budget of a transaction, retries included, when none is set.
+// It matches the one used by the driver.
+const DefaultTransactionTimeout = 120 * time.Second
+
+const (
+	labelTransientTransaction = "TransientTransactionError"
+	labelUnknownCommitResult  = "UnknownTransactionCommitResult"
+)
+
+// TransactionOptions configures DialTransaction. Nil fields keep the client defaults.
+type TransactionOptions struct {
+	ReadConcern    *readconcern.ReadConcern
+	WriteConcern   *writeconcern.WriteConcern
+	ReadPreference *readpref.ReadPref
+	// Timeout is the overall budget of the transaction, retries included.
+	Timeout time.Duration
+	// Backoff between retries, DefaultBackoff if it is the zero value. MaxAttempts is
+	// ignored, the transaction is retried until Timeout.
+	Backoff Backoff
+}
+
+// TransactionFunc runs a transaction and returns the number of attempts it took.
+type TransactionFunc func(context.Context, *mongo.Client) (attempts *int, err error)
+
+type transactionKey struct{}
+
+// inTransaction reports whether ctx belongs to a running transaction.
+func inTransaction(ctx context.Context) bool {
+	in, _ := ctx.Value(transactionKey{}).(bool)
+	return in
+}
+
+// Exec adapts any query func, like the ones returned by the Do* functions, to a QueryFunc
+// discarding its result. Capture the result in a closure if you need it.
+func Exec[T any](query func(context.Context, *mongo.Client) (*T, error)) QueryFunc {
+	return func(ctx context.Context, c *mongo.Client) error {
+		_, err := query(ctx, c)
+		return err
+	}
+}
+
+// Transaction runs funcs in order inside a multi-document transaction. The whole
+// transaction is retried on TransientTransactionError and the commit on
+// UnknownTransactionCommitResult, until opts.Timeout is exhausted.
+func Transaction(opts *TransactionOptions, funcs ...QueryFunc) TransactionFunc {
+	if opts == nil {
+		opts = &TransactionOptions{}
+	}
+
+	return Instrument(Operation{Name: "transaction"}, func(ctx context.Context, c *mongo.Client) (*int, error) {
+		timeout := opts.Timeout
+		if timeout == 0 {
+			timeout = DefaultTransactionTimeout
+		}
+
+		ctx, cl := context.WithTimeout(ctx, timeout)
+		defer cl()
+
+		sess, err := c.StartSession()
+		if err != nil {
+			return nil, err
+		}
+		defer sess.EndSession(context.Background())
+
+		txnOpts := options.Transaction().
+			SetReadConcern(opts.ReadConcern).
+			SetWriteConcern(opts.WriteConcern).
+			SetReadPreference(opts.ReadPreference)
+
+		backoff := opts.Backoff.orDefault()
+		sessCtx := mongo.NewSessionContext(context.WithValue(ctx, transactionKey{}, true), sess)
+
+		for attempt := 0; ; attempt++ {
+			attempts := attempt + 1
+
+			err := runTransaction(sessCtx, c, sess, txnOpts, backoff, funcs)
+			if err == nil {
+				return &attempts, nil
+			}
+
+			if !hasErrorLabel(err, labelTransientTransaction) || backoff.Wait(ctx, attempt) != nil {
+				return &attempts, err
+			}
+		}
+	})
+}
+
+func runTransaction(ctx mongo.SessionContext, c *mongo.Client, sess mongo.Session, txnOpts *options.TransactionOptions, backoff Backoff, funcs []QueryFunc) error {
+	if err := sess.StartTransaction(txnOpts); err != nil {
+		return err
+	}
+
+	for _, fn := range funcs {
+		if err := fn(ctx, c); err != nil {
+			_ = sess.AbortTransaction(context.Background())
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := sess.CommitTransaction(ctx)
+		if err == nil || !hasErrorLabel(err, labelUnknownCommitResult) {
+			return translateError(err)
+		}
+
+		if backoff.Wait(ctx, attempt) != nil {
+			return translateError(err)
+		}
+	}
+}
+
+// DialTransaction runs funcs inside a transaction using the default client.
+func DialTransaction(ctx context.Context, opts *TransactionOptions, funcs ...QueryFunc) error {
+	_, err := DialConnection(ctx, Transaction(opts, funcs...))
+	return err
+}
+
+func hasErrorLabel(err error, label string) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorLabel(label)
+}
@@ -0,0 +1,36 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestHasErrorLabel(t *testing.T) {
+	transient := mongo.CommandError{Labels: []string{labelTransientTransaction}}
+
+	assert.True(t, hasErrorLabel(transient, labelTransientTransaction))
+	assert.True(t, hasErrorLabel(fmt.Errorf("moving order: %w", transient), labelTransientTransaction))
+	assert.False(t, hasErrorLabel(transient, labelUnknownCommitResult))
+	assert.False(t, hasErrorLabel(context.Canceled, labelTransientTransaction))
+}
+
+func TestRetryIsSkippedInsideTransactions(t *testing.T) {
+	t.Cleanup(ClearMiddlewares)
+	Use(Retry(RetryPolicy{Backoff: Backoff{Initial: time.Millisecond, MaxAttempts: 3}}))
+
+	calls := 0
+	query := Exec(Instrument(Operation{Name: "find", Idempotent: true}, func(ctx context.Context, c *mongo.Client) (*int, error) {
+		calls++
+		return nil, mongo.CommandError{Code: codeNotWritablePrimary}
+	}))
+
+	ctx := context.WithValue(context.TODO(), transactionKey{}, true)
+
+	assert.Error(t, query(ctx, nil))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,321 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidUpdate is returned when an update document has fields which are not update
+// operators, which would replace the whole document, or a replacement has operators.
+var ErrInvalidUpdate = errors.New("invalid update document")
+
+// UpdateResultFunc returns the matched, modified and upserted counts of an update.
+type UpdateResultFunc func(context.Context, *mongo.Client) (*mongo.UpdateResult, error)
+
+// UpdateOption configures an update or a replace.
+type UpdateOption func(*updateSettings)
+
+type updateSettings struct {
+	upsert       *bool
+	arrayFilters []any
+	hint         any
+	collation    *options.Collation
+	version      *int64
+}
+
+// WithUpsert inserts a document when the filter matches nothing.
+func WithUpsert(upsert bool) UpdateOption {
+	return func(s *updateSettings) { s.upsert = &upsert }
+}
+
+// WithArrayFilters sets the filters which choose the array elements to update, like
+// bson.D{{Key: "elem.grade", Value: bson.D{{Key: "$gte", Value: 85}}}}. Ignored by replaces.
+func WithArrayFilters(filters ...any) UpdateOption {
+	return func(s *updateSettings) { s.arrayFilters = filters }
+}
+
+// WithHint sets the index to use, by name or by its key document.
+func WithHint(hint any) UpdateOption {
+	return func(s *updateSettings) { s.hint = hint }
+}
+
+// WithCollation sets the collation used to compare strings.
+func WithCollation(collation *options.Collation) UpdateOption {
+	return func(s *updateSettings) { s.collation = collation }
+}
+
+// WithVersion only updates the document when it is at version, returning a *ConflictError
+// otherwise. Used by DoUpdateOneOf, DoUpdateManyOf and Repository.Update when T has a version
+// field, ignored by the other updates.
+func WithVersion(version int64) UpdateOption {
+	return func(s *updateSettings) { s.version = &version }
+}
+
+func newUpdateSettings(opts []UpdateOption) updateSettings {
+	var s updateSettings
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+func (s updateSettings) updateOptions() *options.UpdateOptions {
+	uo := options.Update()
+	if s.upsert != nil {
+		uo.SetUpsert(*s.upsert)
+	}
+	if s.arrayFilters != nil {
+		uo.SetArrayFilters(options.ArrayFilters{Filters: s.arrayFilters})
+	}
+	if s.hint != nil {
+		uo.SetHint(s.hint)
+	}
+	if s.collation != nil {
+		uo.SetCollation(s.collation)
+	}
+	return uo
+}
+
+func (s updateSettings) replaceOptions() *options.ReplaceOptions {
+	ro := options.Replace()
+	if s.upsert != nil {
+		ro.SetUpsert(*s.upsert)
+	}
+	if s.hint != nil {
+		ro.SetHint(s.hint)
+	}
+	if s.collation != nil {
+		ro.SetCollation(s.collation)
+	}
+	return ro
+}
+
+// DoUpdateOne applies update to the first document matching filter. The update is sent as it
+// is: use DoUpdateOneOf for documents with a version or an updated timestamp.
+func DoUpdateOne(db, col string, filter, update any, opts ...UpdateOption) UpdateResultFunc {
+	return Instrument(Operation{Name: "updateOne", Database: db, Collection: col}, func(ctx context.Context, c *mongo.Client) (*mongo.UpdateResult, error) {
+		if err := validateUpdate(update); err != nil {
+			return nil, err
+		}
+
+		ur, err := c.Database(db).Collection(col).UpdateOne(ctx, visibleFilter(ctx, db, col, filter), update, newUpdateSettings(opts).updateOptions())
+		return ur, translateError(err)
+	})
+}
+
+// DoUpdateMany applies update to every document matching filter. The update is sent as it
+// is: use DoUpdateManyOf for documents with a version or an updated timestamp.
+func DoUpdateMany(db, col string, filter, update any, opts ...UpdateOption) UpdateResultFunc {
+	return Instrument(Operation{Name: "updateMany", Database: db, Collection: col}, func(ctx context.Context, c *mongo.Client) (*mongo.UpdateResult, error) {
+		if err := validateUpdate(update); err != nil {
+			return nil, err
+		}
+
+		ur, err := c.Database(db).Collection(col).UpdateMany(ctx, visibleFilter(ctx, db, col, filter), update, newUpdateSettings(opts).updateOptions())
+		return ur, translateError(err)
+	})
+}
+
+// DoUpdateOneOf is DoUpdateOne for documents T: the version and updated timestamp of T, if
+// any, are updated too. With WithVersion the document must be at that version, or a
+// *ConflictError is returned.
+func DoUpdateOneOf[T any](db, col string, filter, update any, opts ...UpdateOption) UpdateResultFunc {
+	return Instrument(Operation{Name: "updateOne", Database: db, Collection: col}, func(ctx context.Context, c *mongo.Client) (*mongo.UpdateResult, error) {
+		return updateOf[T](ctx, c.Database(db).Collection(col), visibleFilter(ctx, db, col, filter), update, false, newUpdateSettings(opts))
+	})
+}
+
+// DoUpdateManyOf is DoUpdateMany for documents T, see DoUpdateOneOf. With WithVersion only the
+// documents at that version are updated, no *ConflictError is returned for the others.
+func DoUpdateManyOf[T any](db, col string, filter, update any, opts ...UpdateOption) UpdateResultFunc {
+	return Instrument(Operation{Name: "updateMany", Database: db, Collection: col}, func(ctx context.Context, c *mongo.Client) (*mongo.UpdateResult, error) {
+		return updateOf[T](ctx, c.Database(db).Collection(col), visibleFilter(ctx, db, col, filter), update, true, newUpdateSettings(opts))
+	})
+}
+
+func updateOf[T any](ctx context.Context, coll *mongo.Collection, filter, update any, many bool, s updateSettings) (*mongo.UpdateResult, error) {
+	if err := beforeUpdate(ctx, &update); err != nil {
+		return nil, err
+	}
+
+	query, change, err := prepareUpdateOf[T](filter, update, s)
+	if err != nil {
+		return nil, err
+	}
+
+	var ur *mongo.UpdateResult
+	if many {
+		ur, err = coll.UpdateMany(ctx, query, change, s.updateOptions())
+	} else {
+		ur, err = coll.UpdateOne(ctx, query, change, s.updateOptions())
+	}
+
+	vf, versioned := versionFieldOf[T]()
+	if many || !versioned || s.version == nil {
+		return ur, translateError(err)
+	}
+
+	// Like replaces, an upsert which did not match the version tries to insert the same _id again.
+	if errors.Is(translateError(err), ErrDuplicateKey) || (err == nil && ur.MatchedCount == 0 && ur.UpsertedCount == 0) {
+		if conflict := vf.conflict(ctx, coll, filter, *s.version); conflict != nil {
+			return nil, conflict
+		}
+	}
+
+	return ur, translateError(err)
+}
+
+// prepareUpdateOf returns the filter and the update sent for an update of documents T.
+func prepareUpdateOf[T any](filter, update any, s updateSettings) (query, change any, err error) {
+	if err := validateUpdate(update); err != nil {
+		return nil, nil, err
+	}
+
+	if change, err = prepareUpdate[T](update); err != nil {
+		return nil, nil, err
+	}
+
+	query = filter
+	if vf, versioned := versionFieldOf[T](); versioned && s.version != nil {
+		query = vf.filter(filter, *s.version)
+	}
+
+	return query, change, nil
+}
+
+// DoReplaceOne replaces the first document matching filter with replacement. When T has a
+// version field the document must be at the version of replacement, or a *ConflictError is
+// returned, and the version stored is incremented. Such replaces are not idempotent: after an
+// ambiguous failure, executing them again would conflict with their own change.
+func DoReplaceOne[T any](db, col string, filter any, replacement T, opts ...UpdateOption) UpdateResultFunc {
+	_, versioned := versionFieldOf[T]()
+
+	return Instrument(Operation{Name: "replaceOne", Database: db, Collection: col, Idempotent: !versioned}, func(ctx context.Context, c *mongo.Client) (*mongo.UpdateResult, error) {
+		if err := validateReplacement(replacement); err != nil {
+			return nil, err
+		}
+
+		coll := c.Database(db).Collection(col)
+		filter := visibleFilter(ctx, db, col, filter)
+		doc := stampReplace(replacement)
+		if err := beforeUpdate(ctx, &doc); err != nil {
+			return nil, err
+		}
+
+		vf, versioned := versionFieldOf[T]()
+		if !versioned {
+			ur, err := coll.ReplaceOne(ctx, filter, doc, newUpdateSettings(opts).replaceOptions())
+			return ur, translateError(err)
+		}
+
+		doc, expected := bumpVersion(vf, doc)
+
+		ur, err := coll.ReplaceOne(ctx, vf.filter(filter, expected), doc, newUpdateSettings(opts).replaceOptions())
+		if errors.Is(translateError(err), ErrDuplicateKey) {
+			// An upsert which did not match the version tries to insert the same _id again.
+			if conflict := vf.conflict(ctx, coll, filter, expected); conflict != nil {
+				return nil, conflict
+			}
+		}
+		if err != nil {
+			return ur, translateError(err)
+		}
+
+		if ur.MatchedCount == 0 && ur.UpsertedCount == 0 {
+			if conflict := vf.conflict(ctx, coll, filter, expected); conflict != nil {
+				return nil, conflict
+			}
+		}
+
+		return ur, nil
+	})
+}
+
+// validateUpdate checks every top level field of update is an operator, like $set. Arrays are
+// aggregation pipelines and are accepted as long as they are not empty.
+func validateUpdate(update any) error {
+	if isPipeline(update) {
+		if reflect.ValueOf(update).Len() == 0 {
+			return fmt.Errorf("%w: empty pipeline", ErrInvalidUpdate)
+		}
+		return nil
+	}
+
+	keys, err := topLevelKeys(update)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("%w: no update operators", ErrInvalidUpdate)
+	}
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "$") {
+			return fmt.Errorf("%w: field %q is not an update operator, use $set to change it", ErrInvalidUpdate, key)
+		}
+	}
+
+	return nil
+}
+
+// validateReplacement checks replacement has no update operators.
+func validateReplacement(replacement any) error {
+	keys, err := topLevelKeys(replacement)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if strings.HasPrefix(key, "$") {
+			return fmt.Errorf("%w: replacement contains the operator %q, use an update", ErrInvalidUpdate, key)
+		}
+	}
+
+	return nil
+}
+
+func isPipeline(v any) bool {
+	switch v.(type) {
+	case nil, bson.D, bson.Raw:
+		// bson.D and bson.Raw are slices too, but documents.
+		return false
+	}
+
+	kind := reflect.ValueOf(v).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+func topLevelKeys(doc any) ([]string, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("%w: nil document", ErrInvalidUpdate)
+	}
+
+	raw, ok := doc.(bson.Raw)
+	if !ok {
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUpdate, err)
+		}
+		raw = data
+	}
+
+	elems, err := raw.Elements()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUpdate, err)
+	}
+
+	keys := make([]string, len(elems))
+	for i := range elems {
+		keys[i] = elems[i].Key()
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,96 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestValidateUpdate(t *testing.T) {
+	var testCases = []struct {
+		description string
+		update      any
+		wantErr     bool
+	}{
+		{
+			description: "operators in a bson.D are valid",
+			update:      bson.D{{Key: "$set", Value: bson.D{{Key: "age", Value: 3}}}, {Key: "$inc", Value: bson.D{{Key: "visits", Value: 1}}}},
+		},
+		{
+			description: "operators in a bson.M are valid",
+			update:      bson.M{"$unset": bson.M{"age": ""}},
+		},
+		{
+			description: "pipelines are valid",
+			update:      mongo.Pipeline{{{Key: "$set", Value: bson.D{{Key: "total", Value: 1}}}}},
+		},
+		{
+			description: "plain fields would replace the document",
+			update:      bson.D{{Key: "age", Value: 3}},
+			wantErr:     true,
+		},
+		{
+			description: "mixing operators and plain fields is invalid",
+			update:      bson.D{{Key: "$set", Value: bson.D{{Key: "age", Value: 3}}}, {Key: "name", Value: "x"}},
+			wantErr:     true,
+		},
+		{
+			description: "structs are documents without operators",
+			update:      Person{Name: "x"},
+			wantErr:     true,
+		},
+		{
+			description: "empty documents are invalid",
+			update:      bson.D{},
+			wantErr:     true,
+		},
+		{
+			description: "empty pipelines are invalid",
+			update:      mongo.Pipeline{},
+			wantErr:     true,
+		},
+		{
+			description: "nil is invalid",
+			update:      nil,
+			wantErr:     true,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.description, func(t *testing.T) {
+			err := validateUpdate(tCase.update)
+
+			if tCase.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidUpdate)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateReplacement(t *testing.T) {
+	assert.NoError(t, validateReplacement(Person{Name: "x"}))
+	assert.ErrorIs(t, validateReplacement(bson.D{{Key: "$set", Value: bson.D{{Key: "age", Value: 3}}}}), ErrInvalidUpdate)
+}
+
+func TestUpdateOptions(t *testing.T) {
+	collation := &options.Collation{Locale: "es"}
+	filters := bson.D{{Key: "elem.grade", Value: bson.D{{Key: "$gte", Value: 85}}}}
+
+	s := newUpdateSettings([]UpdateOption{WithUpsert(true), WithArrayFilters(filters), WithHint("age_1"), WithCollation(collation)})
+
+	uo := s.updateOptions()
+	assert.True(t, *uo.Upsert)
+	assert.Equal(t, []any{filters}, uo.ArrayFilters.Filters)
+	assert.Equal(t, "age_1", uo.Hint)
+	assert.Equal(t, collation, uo.Collation)
+
+	ro := s.replaceOptions()
+	assert.True(t, *ro.Upsert)
+	assert.Equal(t, "age_1", ro.Hint)
+	assert.Equal(t, collation, ro.Collation)
+}
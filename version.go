@@ -0,0 +1,118 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// versionTag marks the integer field holding the version of a document, used for optimistic
+// locking:
+//
+//	Version int64 `bson:"_v" mongo:"version"`
+//
+// Replacing a document through DoReplaceOne or DoFindOneAndReplace only succeeds while the
+// stored version is the one of the replacement, and increments it. DoUpdateOneOf,
+// DoUpdateManyOf, DoFindOneAndUpdate and Repository.Update increment it on every update, and
+// check it when given the expected version. The untyped DoUpdateOne, DoUpdateMany and
+// DoFindAndUpdate know nothing of T, so versioned documents must not be updated with them:
+// a stale replace would then succeed.
+const versionTag = "version"
+
+// versionField locates the version field of a struct.
+type versionField docField
+
+// versionFieldOf returns the version field of T, if it has one.
+func versionFieldOf[T any]() (*versionField, bool) {
+	f := taggedField(reflect.TypeOf((*T)(nil)).Elem(), versionTag, isSignedInt)
+	return (*versionField)(f), f != nil
+}
+
+func isSignedInt(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+// bumpVersion returns a copy of doc with the version incremented, and the version doc had.
+// doc itself is not modified.
+func bumpVersion[T any](vf *versionField, doc T) (T, int64) {
+	rv := reflect.ValueOf(&doc).Elem()
+
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return doc, 0
+		}
+		copied := reflect.New(rv.Type().Elem())
+		copied.Elem().Set(rv.Elem())
+		rv.Set(copied)
+	}
+
+	for rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+
+	field := rv.FieldByIndex(vf.index)
+	expected := field.Int()
+	field.SetInt(expected + 1)
+
+	return doc, expected
+}
+
+// filter restricts filter to the documents at version expected. Documents without the field
+// are at version 0.
+func (vf *versionField) filter(filter any, expected int64) any {
+	if expected == 0 {
+		return andAny(filter, bson.D{{Key: vf.name, Value: bson.D{{Key: "$in", Value: bson.A{0, nil}}}}})
+	}
+
+	return andAny(filter, bson.D{{Key: vf.name, Value: expected}})
+}
+
+// increment adds the increment of the version to update, made of update operators or a
+// pipeline.
+func (vf *versionField) increment(update any) (any, error) {
+	if isPipeline(update) {
+		return appendStage(update, bson.D{{Key: "$set", Value: bson.D{{Key: vf.name, Value: bson.D{
+			{Key: "$add", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$" + vf.name, 0}}}, 1}},
+		}}}}}), nil
+	}
+
+	doc, err := updateDocument(update)
+	if err != nil {
+		return nil, err
+	}
+
+	return withOperator(doc, "$inc", bson.E{Key: vf.name, Value: 1})
+}
+
+// conflict returns a *ConflictError when the document matching filter is not at version
+// expected, or nil if there is no such document.
+func (vf *versionField) conflict(ctx context.Context, coll *mongo.Collection, filter any, expected int64) error {
+	var current bson.Raw
+
+	err := coll.FindOne(ctx, filter, options.FindOne().SetProjection(bson.D{{Key: vf.name, Value: 1}})).Decode(&current)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return translateError(err)
+	}
+
+	conflict := &ConflictError{Expected: expected}
+	if value, err := current.LookupErr(vf.name); err == nil {
+		conflict.Current, _ = value.AsInt64OK()
+	}
+
+	if conflict.Current == expected {
+		return nil
+	}
+
+	return conflict
+}
@@ -0,0 +1,131 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type versioned struct {
+	Name    string `bson:"name"`
+	Version int64  `bson:"_v" mongo:"version"`
+}
+
+type versionedInline struct {
+	ID    string `bson:"_id"`
+	Inner struct {
+		Rev int `mongo:"version"`
+	} `bson:",inline"`
+}
+
+func TestVersionFieldOf(t *testing.T) {
+	vf, ok := versionFieldOf[versioned]()
+	assert.True(t, ok)
+	assert.Equal(t, &versionField{index: []int{1}, name: "_v"}, vf)
+
+	vf, ok = versionFieldOf[*versionedInline]()
+	assert.True(t, ok)
+	assert.Equal(t, &versionField{index: []int{1, 0}, name: "rev"}, vf)
+
+	_, ok = versionFieldOf[Person]()
+	assert.False(t, ok)
+
+	_, ok = versionFieldOf[bson.M]()
+	assert.False(t, ok)
+}
+
+func TestBumpVersion(t *testing.T) {
+	vf, _ := versionFieldOf[versioned]()
+
+	doc := versioned{Name: "x", Version: 3}
+	bumped, expected := bumpVersion(vf, doc)
+	assert.Equal(t, int64(3), expected)
+	assert.Equal(t, versioned{Name: "x", Version: 4}, bumped)
+	assert.Equal(t, int64(3), doc.Version)
+
+	ptr := &versioned{Version: 1}
+	bumpedPtr, expected := bumpVersion(vf, ptr)
+	assert.Equal(t, int64(1), expected)
+	assert.Equal(t, int64(2), bumpedPtr.Version)
+	assert.Equal(t, int64(1), ptr.Version, "the caller's document is not modified")
+}
+
+func TestVersionFilterAndIncrement(t *testing.T) {
+	vf := &versionField{name: "_v"}
+	filter := bson.D{{Key: "name", Value: "x"}}
+
+	assert.Equal(t, bson.D{{Key: "$and", Value: bson.A{filter, bson.D{{Key: "_v", Value: int64(2)}}}}}, vf.filter(filter, 2))
+	assert.Equal(t, bson.D{{Key: "_v", Value: bson.D{{Key: "$in", Value: bson.A{0, nil}}}}}, vf.filter(nil, 0))
+
+	update, err := vf.increment(bson.M{"$set": bson.M{"name": "y"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bson.D{{Key: "$set", Value: bson.D{{Key: "name", Value: "y"}}}, {Key: "$inc", Value: bson.D{{Key: "_v", Value: 1}}}}, update)
+
+	update, err = vf.increment(bson.D{{Key: "$inc", Value: bson.D{{Key: "count", Value: int32(1)}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bson.D{{Key: "$inc", Value: bson.D{{Key: "count", Value: int32(1)}, {Key: "_v", Value: 1}}}}, update)
+
+	update, err = vf.increment(mongo.Pipeline{{{Key: "$set", Value: filter}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, update, 2)
+}
+
+func TestPrepareUpdateOf(t *testing.T) {
+	filter := bson.D{{Key: "name", Value: "x"}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "name", Value: "y"}}}}
+
+	query, change, err := prepareUpdateOf[versioned](filter, update, newUpdateSettings([]UpdateOption{WithVersion(3)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bson.D{{Key: "$and", Value: bson.A{filter, bson.D{{Key: "_v", Value: int64(3)}}}}}, query)
+	assert.Equal(t, bson.D{{Key: "$set", Value: bson.D{{Key: "name", Value: "y"}}}, {Key: "$inc", Value: bson.D{{Key: "_v", Value: 1}}}}, change)
+
+	query, change, err = prepareUpdateOf[versioned](filter, update, updateSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, filter, query, "the version is only checked when given")
+	assert.Len(t, change, 2)
+
+	query, change, err = prepareUpdateOf[Person](filter, update, newUpdateSettings([]UpdateOption{WithVersion(3)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, filter, query)
+	assert.Equal(t, update, change)
+
+	_, _, err = prepareUpdateOf[versioned](filter, bson.D{{Key: "name", Value: "y"}}, updateSettings{})
+	assert.ErrorIs(t, err, ErrInvalidUpdate)
+}
+
+func TestReplaceOneIdempotency(t *testing.T) {
+	t.Cleanup(ClearMiddlewares)
+
+	var ops []Operation
+	Use(func(next QueryFunc) QueryFunc {
+		return func(ctx context.Context, c *mongo.Client) error {
+			op, _ := OperationFromContext(ctx)
+			ops = append(ops, op)
+			return errors.New("not sent")
+		}
+	})
+
+	DoReplaceOne(crudTestDb, "col", bson.D{}, Person{})(context.TODO(), nil)
+	DoReplaceOne(crudTestDb, "col", bson.D{}, versioned{})(context.TODO(), nil)
+
+	if assert.Len(t, ops, 2) {
+		assert.True(t, ops[0].Idempotent)
+		assert.False(t, ops[1].Idempotent, "a versioned replace conflicts with itself when executed again")
+	}
+}